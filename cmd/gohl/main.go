@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"strings"
+	"os/signal"
+	"path/filepath"
+	"syscall"
 	"time"
 
 	"github.com/pterm/pterm"
@@ -13,7 +15,10 @@ import (
 
 	"github.com/danielvollbro/gohl/internal/client"
 	"github.com/danielvollbro/gohl/internal/game"
+	"github.com/danielvollbro/gohl/internal/provider/binary"
 	"github.com/danielvollbro/gohl/internal/registry"
+	"github.com/danielvollbro/gohl/internal/sandbox"
+	"github.com/danielvollbro/gohl/internal/scan"
 	"github.com/danielvollbro/gohl/internal/storage"
 	"github.com/danielvollbro/gohl/internal/ui"
 
@@ -21,6 +26,8 @@ import (
 )
 
 func main() {
+	sandbox.Init()
+
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
@@ -43,6 +50,9 @@ var scanCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		useJson, _ := cmd.Flags().GetBool("json")
 		console := ui.New(useJson)
+		if useJson {
+			registry.Output = os.Stderr
+		}
 
 		console.RenderLogo()
 
@@ -58,10 +68,23 @@ var scanCmd = &cobra.Command{
 			enabledProviders = []string{"system"}
 		}
 
+		scoring, err := loadScoring()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "invalid 'scoring' section in gohl.yaml:", err)
+			os.Exit(1)
+		}
+
+		ladder, err := loadLadder()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "invalid rank ladder:", err)
+			os.Exit(1)
+		}
+
 		console.Spacer()
 
-		ctx := context.Background()
-		var allReports []*api.ScanReport
+		defer registry.Shutdown()
+
+		var jobs []scan.Job
 		for _, name := range enabledProviders {
 			scanner, err := registry.GetProvider(name)
 			if err != nil {
@@ -69,26 +92,73 @@ var scanCmd = &cobra.Command{
 				continue
 			}
 
-			console.PrintSuccess("Enabled provider: %s\n", name)
+			info := scanner.Info()
+			if info.Version != "" {
+				console.PrintSuccess("Enabled provider: %s (%s %s)\n", name, info.Name, info.Version)
+			} else {
+				console.PrintSuccess("Enabled provider: %s (%s)\n", name, info.Name)
+			}
 
-			cfg := registry.GetConfig(name)
+			jobs = append(jobs, scan.Job{
+				Name:    name,
+				Scanner: scanner,
+				Config:  registry.GetConfig(name),
+				Timeout: viper.GetDuration(name + ".timeout"),
+			})
+		}
 
-			info := scanner.Info()
-			scanSpinner, _ := console.StartSpinner(fmt.Sprintf("Running %s...", info.Name))
+		names := make([]string, len(jobs))
+		for i, job := range jobs {
+			names[i] = job.Name
+		}
 
-			report, err := scanner.Analyze(ctx, cfg)
-			if err != nil {
-				if scanSpinner != nil {
-					scanSpinner.Fail(fmt.Sprintf("%s failed: %v", info.Name, err))
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		progress := console.StartProgress(names)
+		results := scan.Run(ctx, jobs, viper.GetInt("concurrency"), func(e scan.Event) {
+			switch e.State {
+			case scan.StateRunning:
+				if e.Progress != nil {
+					progress.Update(e.Name, *e.Progress)
+				} else {
+					progress.Running(e.Name)
 				}
-				continue
+			case scan.StateDone:
+				progress.Done(e.Name, "complete")
+			case scan.StateFailed:
+				progress.Fail(e.Name, fmt.Sprintf("failed: %v", e.Err))
 			}
+		})
+		progress.Stop()
 
-			if scanSpinner != nil {
-				scanSpinner.Success(fmt.Sprintf("%s complete", info.Name))
+		var allReports []*api.ScanReport
+		complete := make(map[string]bool)
+		var warnings []game.Warning
+		var runs []game.ProviderRun
+		for i, result := range results {
+			run := game.ProviderRun{
+				Name:     result.Name,
+				Plugin:   jobs[i].Scanner.Info(),
+				Duration: result.Duration.Round(time.Millisecond).String(),
+			}
+			if provider, ok := jobs[i].Scanner.(*binary.BinaryProvider); ok {
+				run.Protocol = provider.Handshake(context.Background()).Negotiate()
 			}
 
-			allReports = append(allReports, report)
+			if result.Err != nil {
+				run.Error = result.Err.Error()
+				run.Partial = result.Partial
+			}
+			if result.Report != nil {
+				warnings = append(warnings, game.Validate(result.Name, result.Report)...)
+				scoring.AliasProvider(result.Name, result.Report.PluginID)
+				allReports = append(allReports, result.Report)
+				if result.Err == nil {
+					complete[result.Report.PluginID] = true
+				}
+			}
+			runs = append(runs, run)
 		}
 
 		console.Spacer()
@@ -98,23 +168,83 @@ var scanCmd = &cobra.Command{
 			labID = "default-lab" // Fallback
 		}
 
-		grandReport := game.CompileReport(allReports, labID)
+		if strict, _ := cmd.Flags().GetBool("strict"); strict && len(warnings) > 0 {
+			for _, warning := range warnings {
+				fmt.Fprintln(os.Stderr, "invalid provider output:", warning)
+			}
+			fmt.Fprintf(os.Stderr, "scan failed: %d validation problem(s) in strict mode\n", len(warnings))
+			registry.Shutdown()
+			os.Exit(1)
+		}
+
+		grandReport := game.CompileReport(allReports, labID, scoring, ladder)
+		grandReport.Providers = runs
+		grandReport.Warnings = warnings
 
+		now := time.Now()
+
+		history, err := storage.LoadHistory(now.AddDate(0, 0, -storage.HistoryDays))
+		if err != nil {
+			console.PrintWarning("Could not read history: %v", err)
+		}
+
+		// Reports from before weighted scoring have no categories and are
+		// not comparable.
 		previousScore := -1
-		lastReport, err := storage.LoadLatest()
-		if err == nil && lastReport != nil {
-			previousScore = lastReport.TotalScore
+		if len(history) > 0 && len(history[len(history)-1].Categories) > 0 {
+			previousScore = history[len(history)-1].Score
+		}
+
+		quests, questsErr := storage.LoadQuests()
+		if questsErr != nil {
+			console.PrintWarning("Could not read quests: %v", questsErr)
 		}
+		quests, questUpdate := game.UpdateQuests(quests, grandReport.PluginReports, complete, scoring, now)
+		grandReport.Quests = &questUpdate
+
+		unlocked, achievementsErr := storage.LoadAchievements()
+		if achievementsErr != nil {
+			console.PrintWarning("Could not read achievements: %v", achievementsErr)
+		}
+		grandReport.Achievements = game.UnlockAchievements(grandReport, history, unlocked, now)
+
+		ledger, ledgerErr := storage.LoadLedger()
+		if ledgerErr != nil {
+			console.PrintWarning("Could not read XP ledger: %v", ledgerErr)
+		}
+		ledger, xp := game.AwardXP(ledger, questUpdate, grandReport, history, now)
+		grandReport.XP = &xp
 
 		console.PrintFinalResults(grandReport, useJson, previousScore)
 
 		if err := storage.Save(grandReport); err != nil {
 			console.PrintWarning("Could not save history: %v", err)
 		}
+		// Do not overwrite state that could not be read.
+		if questsErr == nil {
+			if err := storage.SaveQuests(quests); err != nil {
+				console.PrintWarning("Could not save quests: %v", err)
+			}
+		}
+		if achievementsErr == nil {
+			if err := storage.SaveAchievements(grandReport.Achievements); err != nil {
+				console.PrintWarning("Could not save achievements: %v", err)
+			}
+		}
+		if ledgerErr == nil {
+			if err := storage.SaveLedger(ledger); err != nil {
+				console.PrintWarning("Could not save XP ledger: %v", err)
+			}
+		}
 
 		// --- CLOUD UPLOAD ---
 		shouldSubmit, _ := cmd.Flags().GetBool("submit")
 
+		if shouldSubmit && viper.GetBool("offline") {
+			console.PrintWarning("Offline mode enabled, skipping upload")
+			shouldSubmit = false
+		}
+
 		if shouldSubmit {
 			console.Spacer()
 
@@ -140,23 +270,58 @@ var scanCmd = &cobra.Command{
 	},
 }
 
-func getDockerConfig() map[string]string {
-	cfg := make(map[string]string)
-	ignoredContainers := viper.GetStringSlice("docker.ignore")
-	if len(ignoredContainers) > 0 {
-		cfg["ignore"] = strings.Join(ignoredContainers, ",")
+func loadScoring() (game.Scoring, error) {
+	var scoring game.Scoring
+	if err := viper.UnmarshalKey("scoring", &scoring); err != nil {
+		return scoring, err
 	}
-	return cfg
+	return scoring, scoring.Validate()
+}
+
+// loadLadder reads the 'ranks' list from the file named by 'rank_theme', or
+// from gohl.yaml itself, falling back to the built-in ladder.
+func loadLadder() (game.Ladder, error) {
+	config := viper.GetViper()
+	if theme := viper.GetString("rank_theme"); theme != "" {
+		if !filepath.IsAbs(theme) {
+			theme = filepath.Join(configDir(), theme)
+		}
+		config = viper.New()
+		config.SetConfigFile(theme)
+		if err := config.ReadInConfig(); err != nil {
+			return nil, err
+		}
+	}
+
+	var ladder game.Ladder
+	if err := config.UnmarshalKey("ranks", &ladder); err != nil {
+		return nil, err
+	}
+	if len(ladder) == 0 {
+		return game.DefaultLadder(), nil
+	}
+
+	for _, rank := range ladder {
+		if rank.Color != "" && !ui.ValidRankColor(rank.Color) {
+			return nil, fmt.Errorf("rank '%s': unknown color '%s'", rank.ID, rank.Color)
+		}
+	}
+	return ladder, ladder.Validate()
 }
 
 func init() {
 	cobra.OnInitialize(initConfig)
 	scanCmd.Flags().Bool("json", false, "Output results as JSON for integrations")
 	scanCmd.Flags().Bool("submit", false, "Upload results to the configured server")
+	scanCmd.Flags().Bool("strict", false, "Fail the scan if a provider returns invalid results instead of fixing them up")
+	rootCmd.PersistentFlags().Bool("offline", false, "Only use cached providers, never contact the network")
+	viper.BindPFlag("offline", rootCmd.PersistentFlags().Lookup("offline"))
 	rootCmd.AddCommand(scanCmd)
 }
 
 func initConfig() {
+	viper.SetDefault("concurrency", scan.DefaultWorkers)
+	viper.SetDefault("lab_id", "default-lab")
 	viper.SetConfigName("gohl")
 	viper.SetConfigType("yaml")
 	viper.AddConfigPath(".")
@@ -166,4 +331,20 @@ func initConfig() {
 			fmt.Println("Config file error:", err)
 		}
 	}
+
+	registry.LockFilePath = filepath.Join(configDir(), "gohl.lock")
+
+	pluginDir := viper.GetString("plugin_dir")
+	if pluginDir != "" && !filepath.IsAbs(pluginDir) {
+		pluginDir = filepath.Join(configDir(), pluginDir)
+	}
+	registry.SetPluginDirs(registry.DefaultPluginDirs(pluginDir))
+}
+
+// configDir is where relative paths in gohl.yaml are resolved from.
+func configDir() string {
+	if used := viper.ConfigFileUsed(); used != "" {
+		return filepath.Dir(used)
+	}
+	return "."
 }
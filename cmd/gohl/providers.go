@@ -0,0 +1,287 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/danielvollbro/gohl/internal/registry"
+	"github.com/danielvollbro/gohl/internal/ui"
+)
+
+var providersCmd = &cobra.Command{
+	Use:   "providers",
+	Short: "Manage scan providers",
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		// Keep download progress out of machine-readable output.
+		if asJSON(cmd) {
+			registry.Output = os.Stderr
+		}
+	},
+}
+
+// providerResult is printed for every provider an install, update or remove
+// touched.
+type providerResult struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+	Path    string `json:"path,omitempty"`
+	Digest  string `json:"digest,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+var providersListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List configured, built-in and installed providers",
+	Run: func(cmd *cobra.Command, args []string) {
+		console := ui.New(asJSON(cmd))
+
+		enabled := viper.GetStringSlice("providers")
+		names := registry.Builtins()
+		for _, name := range enabled {
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+
+		statuses, err := registry.Statuses(names)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to read plugin dir:", err)
+			os.Exit(1)
+		}
+
+		type listEntry struct {
+			registry.Status
+			Enabled bool `json:"enabled"`
+		}
+
+		entries := make([]listEntry, 0, len(statuses))
+		rows := [][]string{{"NAME", "ORIGIN", "LOCATION", "VERSION", "INSTALLED", "DIGEST", "ENABLED"}}
+		for _, status := range statuses {
+			entry := listEntry{Status: status, Enabled: slices.Contains(enabled, status.Name)}
+			entries = append(entries, entry)
+
+			origin, location := string(status.Origin), status.Location
+			if status.Error != "" && origin == "" {
+				origin, location = "unknown", status.Error
+			}
+			rows = append(rows, []string{status.Name, origin, location, status.Version, status.Installed, shortDigest(status.Digest), yesNo(entry.Enabled)})
+		}
+
+		if asJSON(cmd) {
+			printJSON(entries)
+			return
+		}
+		console.RenderTable(rows)
+	},
+}
+
+var providersInstallCmd = &cobra.Command{
+	Use:   "install [provider...]",
+	Short: "Download providers as pinned by gohl.yaml and gohl.lock",
+	Run: func(cmd *cobra.Command, args []string) {
+		runInstall(cmd, args, "Installed", registry.InstallProvider)
+	},
+}
+
+var providersUpdateCmd = &cobra.Command{
+	Use:   "update [provider...]",
+	Short: "Resolve providers again and refresh gohl.lock",
+	Run: func(cmd *cobra.Command, args []string) {
+		runInstall(cmd, args, "Updated", registry.UpdateProvider)
+	},
+}
+
+// runInstall applies action to the named providers, or to every configured
+// downloadable provider when none are named.
+func runInstall(cmd *cobra.Command, args []string, verb string, action func(name string) (registry.Installed, error)) {
+	console := ui.New(asJSON(cmd))
+
+	names := args
+	if len(names) == 0 {
+		for _, name := range viper.GetStringSlice("providers") {
+			if desc, err := registry.Describe(name); err == nil && desc.Origin == registry.OriginDownload {
+				names = append(names, name)
+			}
+		}
+	}
+
+	if len(names) == 0 {
+		console.PrintWarning("No downloadable providers configured")
+		if asJSON(cmd) {
+			printJSON([]providerResult{})
+		}
+		return
+	}
+
+	failed := false
+	results := make([]providerResult, 0, len(names))
+	for _, name := range names {
+		item, err := action(name)
+		if err != nil {
+			console.PrintError("Failed to %s %s: %v", cmd.Name(), name, err)
+			results = append(results, providerResult{Name: name, Error: err.Error()})
+			failed = true
+			continue
+		}
+
+		console.PrintSuccess("%s %s (%s) at %s", verb, name, item.Version, item.Path)
+		results = append(results, providerResult{Name: name, Version: item.Version, Path: item.Path, Digest: item.Digest})
+	}
+
+	if asJSON(cmd) {
+		printJSON(results)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+var providersRemoveCmd = &cobra.Command{
+	Use:   "remove [provider...]",
+	Short: "Delete downloaded provider binaries from the plugin dir",
+	Run: func(cmd *cobra.Command, args []string) {
+		console := ui.New(asJSON(cmd))
+
+		names := args
+		if all, _ := cmd.Flags().GetBool("all"); all {
+			installed, err := registry.InstalledProviders()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Failed to read plugin dir:", err)
+				os.Exit(1)
+			}
+			names = nil
+			for _, item := range installed {
+				names = append(names, item.Name)
+			}
+		} else if len(names) == 0 {
+			fmt.Fprintln(os.Stderr, "Name the providers to remove, or pass --all")
+			os.Exit(1)
+		}
+
+		failed := false
+		results := make([]providerResult, 0, len(names))
+		for _, name := range names {
+			result := providerResult{Name: name}
+			if item, err := registry.InstalledProvider(name); err == nil {
+				result.Version, result.Path = item.Version, item.Path
+			}
+
+			if err := registry.RemoveProvider(name); err != nil {
+				console.PrintError("Failed to remove %s: %v", name, err)
+				result.Error = err.Error()
+				failed = true
+			} else {
+				console.PrintSuccess("Removed %s", name)
+			}
+			results = append(results, result)
+		}
+
+		if asJSON(cmd) {
+			printJSON(results)
+		}
+		if failed {
+			os.Exit(1)
+		}
+	},
+}
+
+var providersVerifyCmd = &cobra.Command{
+	Use:   "verify [provider...]",
+	Short: "Check installed providers against their recorded digests",
+	Run: func(cmd *cobra.Command, args []string) {
+		console := ui.New(asJSON(cmd))
+
+		names := args
+		if len(names) == 0 {
+			installed, err := registry.InstalledProviders()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Failed to read plugin dir:", err)
+				os.Exit(1)
+			}
+			for _, item := range installed {
+				names = append(names, item.Name)
+			}
+		}
+
+		failed := false
+		results := make([]registry.Verification, 0, len(names))
+		rows := [][]string{{"NAME", "VERSION", "STATUS", "PROBLEMS"}}
+		for _, name := range names {
+			result, err := registry.VerifyProvider(name)
+			if err != nil {
+				result = registry.Verification{Name: name, Problems: []string{err.Error()}}
+			}
+			if !result.OK {
+				failed = true
+			}
+
+			status := "OK"
+			if !result.OK {
+				status = "FAILED"
+			}
+			results = append(results, result)
+			rows = append(rows, []string{name, result.Version, status, fmt.Sprint(len(result.Problems))})
+
+			for _, problem := range result.Problems {
+				console.PrintError("%s: %s", name, problem)
+			}
+		}
+
+		if asJSON(cmd) {
+			printJSON(results)
+		} else if len(results) > 0 {
+			console.RenderTable(rows)
+		} else {
+			console.PrintWarning("No providers installed")
+		}
+
+		if failed {
+			os.Exit(1)
+		}
+	},
+}
+
+func asJSON(cmd *cobra.Command) bool {
+	useJson, _ := cmd.Flags().GetBool("json")
+	return useJson
+}
+
+func printJSON(v interface{}) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error generating JSON:", err)
+		os.Exit(1)
+	}
+	fmt.Println(string(data))
+}
+
+func shortDigest(digest string) string {
+	if len(digest) > 12 {
+		return digest[:12]
+	}
+	return digest
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+func init() {
+	providersCmd.PersistentFlags().Bool("json", false, "Output results as JSON")
+	providersRemoveCmd.Flags().Bool("all", false, "Remove every installed provider")
+
+	providersCmd.AddCommand(providersListCmd)
+	providersCmd.AddCommand(providersInstallCmd)
+	providersCmd.AddCommand(providersUpdateCmd)
+	providersCmd.AddCommand(providersRemoveCmd)
+	providersCmd.AddCommand(providersVerifyCmd)
+	rootCmd.AddCommand(providersCmd)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/danielvollbro/gohl/internal/game"
+	"github.com/danielvollbro/gohl/internal/storage"
+	"github.com/danielvollbro/gohl/internal/ui"
+)
+
+var questsCmd = &cobra.Command{
+	Use:   "quests",
+	Short: "List open and completed quests from earlier scans",
+	Run: func(cmd *cobra.Command, args []string) {
+		console := ui.New(asJSON(cmd))
+
+		status, _ := cmd.Flags().GetString("status")
+		if status != "all" && status != "open" && status != "completed" && status != "retired" {
+			fmt.Fprintf(os.Stderr, "Invalid status '%s', expected open, completed, retired or all\n", status)
+			os.Exit(1)
+		}
+
+		quests, err := storage.LoadQuests()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to read quests:", err)
+			os.Exit(1)
+		}
+
+		filtered := make([]game.Quest, 0, len(quests))
+		for _, quest := range quests {
+			if status == "all" || (status == "open" && quest.IsOpen()) || string(quest.Status) == status {
+				filtered = append(filtered, quest)
+			}
+		}
+		game.SortQuests(filtered)
+
+		if asJSON(cmd) {
+			printJSON(filtered)
+			return
+		}
+
+		if len(filtered) == 0 {
+			console.PrintSuccess("No quests. Run 'gohl scan' to find some.")
+			return
+		}
+
+		now := time.Now()
+		rows := [][]string{{"STATUS", "QUEST", "PROVIDER", "IMPACT", "OPEN FOR", "COMPLETED"}}
+		for _, quest := range filtered {
+			completed := ""
+			if quest.CompletedAt != nil {
+				completed = quest.CompletedAt.Local().Format("2006-01-02 15:04")
+			}
+			rows = append(rows, []string{
+				string(quest.Status),
+				quest.Name,
+				quest.PluginID,
+				fmt.Sprintf("%g", quest.Impact),
+				formatAge(quest.OpenFor(now)),
+				completed,
+			})
+		}
+		console.RenderTable(rows)
+	},
+}
+
+// formatAge shortens a duration to its two largest units, e.g. "3d 4h".
+func formatAge(d time.Duration) string {
+	switch {
+	case d >= 24*time.Hour:
+		return fmt.Sprintf("%dd %dh", d/(24*time.Hour), d%(24*time.Hour)/time.Hour)
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh %dm", d/time.Hour, d%time.Hour/time.Minute)
+	default:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	}
+}
+
+func init() {
+	questsCmd.Flags().Bool("json", false, "Output quests as JSON")
+	questsCmd.Flags().String("status", "all", "Which quests to list: open, completed, retired or all")
+	rootCmd.AddCommand(questsCmd)
+}
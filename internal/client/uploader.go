@@ -7,10 +7,14 @@ import (
 	"net/http"
 	"time"
 
-	api "github.com/danielvollbro/gohl-api"
+	"github.com/danielvollbro/gohl/internal/game"
+	"github.com/danielvollbro/gohl/internal/version"
 )
 
-func UploadReport(url string, report api.GrandReport) error {
+// UploadReport posts a compiled report to the configured server. The server
+// should rank on "score"; "total_score" and "max_score" are raw point sums
+// that depend on which providers a lab runs.
+func UploadReport(url string, report game.Report) error {
 	jsonData, err := json.Marshal(report)
 	if err != nil {
 		return fmt.Errorf("failed to marshal report: %w", err)
@@ -21,7 +25,7 @@ func UploadReport(url string, report api.GrandReport) error {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("User-Agent", "GOHL-CLI/v0.1.0")
+	req.Header.Set("User-Agent", "GOHL-CLI/v"+version.Agent)
 
 	client := &http.Client{Timeout: 10 * time.Second}
 	resp, err := client.Do(req)
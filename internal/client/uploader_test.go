@@ -6,6 +6,8 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/danielvollbro/gohl/internal/game"
+
 	api "github.com/danielvollbro/gohl-api"
 )
 
@@ -19,19 +21,23 @@ func TestUploadReport(t *testing.T) {
 			t.Errorf("Expected Content-Type application/json, got %s", r.Header.Get("Content-Type"))
 		}
 
-		var receivedReport api.GrandReport
+		var receivedReport game.Report
 		if err := json.NewDecoder(r.Body).Decode(&receivedReport); err != nil {
 			t.Errorf("Could not decode body: %v", err)
 		}
+		if receivedReport.Score != 90 || receivedReport.RankID != "pilot" {
+			t.Errorf("Report fields were not uploaded: %+v", receivedReport)
+		}
 
 		w.WriteHeader(http.StatusOK)
 	}))
 
 	defer server.Close()
 
-	dummyReport := api.GrandReport{
-		Rank:       "Test Pilot",
-		TotalScore: 100,
+	dummyReport := game.Report{
+		GrandReport: api.GrandReport{Rank: "Test Pilot", TotalScore: 100},
+		Score:       90,
+		RankID:      "pilot",
 	}
 
 	err := UploadReport(server.URL, dummyReport)
@@ -47,7 +53,7 @@ func TestUploadReport_ServerError(t *testing.T) {
 	}))
 	defer server.Close()
 
-	dummyReport := api.GrandReport{}
+	dummyReport := game.Report{}
 
 	err := UploadReport(server.URL, dummyReport)
 
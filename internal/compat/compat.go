@@ -0,0 +1,60 @@
+package compat
+
+import (
+	"fmt"
+
+	"github.com/danielvollbro/gohl/internal/version"
+)
+
+// Requirements are what a provider release needs from the agent. Releases
+// declare them in a gohl-provider.json asset or in their index entry, and
+// providers can repeat them in their handshake.
+type Requirements struct {
+	// MinAgentVersion is the oldest gohl release the provider works with.
+	MinAgentVersion string `json:"min_agent_version,omitempty"`
+
+	// APIVersion is the gohl-api schema the provider was built against. A
+	// plain version accepts any agent API compatible with it in the caret
+	// sense; a range is used as is.
+	APIVersion string `json:"api_version,omitempty"`
+}
+
+// Check returns an error explaining why this agent cannot run the provider.
+func (r Requirements) Check() error {
+	return r.check(version.Agent, version.API)
+}
+
+func (r Requirements) check(agent, api string) error {
+	if r.MinAgentVersion != "" {
+		min, err := ParseVersion(r.MinAgentVersion)
+		if err != nil {
+			return fmt.Errorf("invalid min_agent_version: %v", err)
+		}
+
+		// Development builds without a release version are not held back.
+		if current, err := ParseVersion(agent); err == nil && current.Compare(min) < 0 {
+			return fmt.Errorf("requires gohl %s or newer (this is %s)", r.MinAgentVersion, agent)
+		}
+	}
+
+	if r.APIVersion != "" {
+		wanted := r.APIVersion
+		if !IsRange(wanted) {
+			wanted = "^" + wanted
+		}
+		constraint, err := ParseConstraint(wanted)
+		if err != nil {
+			return fmt.Errorf("invalid api_version: %v", err)
+		}
+
+		current, err := ParseVersion(api)
+		if err != nil || !constraint.Check(current) {
+			return fmt.Errorf("built for gohl-api %s, agent speaks %s", r.APIVersion, api)
+		}
+	}
+	return nil
+}
+
+func (r Requirements) IsZero() bool {
+	return r == Requirements{}
+}
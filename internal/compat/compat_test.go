@@ -0,0 +1,89 @@
+package compat
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConstraint(t *testing.T) {
+	cases := []struct {
+		constraint, version string
+		want                bool
+	}{
+		{"^1.2", "v1.2.0", true},
+		{"^1.2", "1.9.3", true},
+		{"^1.2", "1.1.9", false},
+		{"^1.2", "2.0.0", false},
+		{"^0.4", "0.4.7", true},
+		{"^0.4", "0.5.0", false},
+		{"^0.0.3", "0.0.4", false},
+		{"~1.2", "1.2.9", true},
+		{"~1.2", "1.3.0", false},
+		{"1.x", "1.7.0", true},
+		{"1.x", "2.0.0", false},
+		{"*", "3.1.4", true},
+		{">=1.2 <1.4", "1.3.5", true},
+		{">=1.2, <1.4", "1.4.0", false},
+		{"^1 || ^3", "3.0.1", true},
+		{"^1 || ^3", "2.0.0", false},
+		{"1.2", "1.2.0", true},
+		{"^1.2", "1.3.0-rc.1", false},
+		{"^1.3.0-rc", "1.3.0-rc.1", true},
+	}
+
+	for _, c := range cases {
+		if got := Satisfies(c.version, c.constraint); got != c.want {
+			t.Errorf("Satisfies(%q, %q) = %v, want %v", c.version, c.constraint, got, c.want)
+		}
+	}
+}
+
+func TestParseConstraint_Invalid(t *testing.T) {
+	for _, raw := range []string{"", "^", ">=one", "1.2.3.4.x"} {
+		if _, err := ParseConstraint(raw); err == nil {
+			t.Errorf("Expected error for %q", raw)
+		}
+	}
+}
+
+func TestIsRange(t *testing.T) {
+	for raw, want := range map[string]bool{
+		"v1.2.0": false,
+		"latest": false,
+		"^1.2":   true,
+		"1.x":    true,
+		">=1":    true,
+		"*":      true,
+	} {
+		if got := IsRange(raw); got != want {
+			t.Errorf("IsRange(%q) = %v, want %v", raw, got, want)
+		}
+	}
+}
+
+func TestRequirements(t *testing.T) {
+	cases := []struct {
+		requires Requirements
+		agent    string
+		problem  string
+	}{
+		{Requirements{}, "0.1.0", ""},
+		{Requirements{MinAgentVersion: "0.2.0"}, "0.1.0", "requires gohl 0.2.0"},
+		{Requirements{MinAgentVersion: "0.2.0"}, "0.2.1", ""},
+		{Requirements{MinAgentVersion: "0.2.0"}, "dev", ""},
+		{Requirements{APIVersion: "0.4.0"}, "0.1.0", ""},
+		{Requirements{APIVersion: "0.3"}, "0.1.0", "built for gohl-api 0.3"},
+		{Requirements{APIVersion: ">=0.3 <1"}, "0.1.0", ""},
+		{Requirements{MinAgentVersion: "soon"}, "0.1.0", "invalid min_agent_version"},
+	}
+
+	for _, c := range cases {
+		err := c.requires.check(c.agent, "0.4.0")
+		switch {
+		case c.problem == "" && err != nil:
+			t.Errorf("%+v on %s: unexpected error %v", c.requires, c.agent, err)
+		case c.problem != "" && (err == nil || !strings.Contains(err.Error(), c.problem)):
+			t.Errorf("%+v on %s: expected %q, got %v", c.requires, c.agent, c.problem, err)
+		}
+	}
+}
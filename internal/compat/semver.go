@@ -0,0 +1,262 @@
+package compat
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is a semantic version. Missing minor and patch numbers parse as 0;
+// Parts records how many were given, which ranges like "^1.2" depend on.
+type Version struct {
+	Major, Minor, Patch int
+	Pre                 string
+	Parts               int
+}
+
+func ParseVersion(s string) (Version, error) {
+	v := Version{}
+	raw := strings.TrimPrefix(strings.TrimSpace(s), "v")
+	raw, _, _ = strings.Cut(raw, "+")
+	raw, v.Pre, _ = strings.Cut(raw, "-")
+
+	parts := strings.Split(raw, ".")
+	if raw == "" || len(parts) > 3 {
+		return Version{}, fmt.Errorf("invalid version %q", s)
+	}
+
+	numbers := []*int{&v.Major, &v.Minor, &v.Patch}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return Version{}, fmt.Errorf("invalid version %q", s)
+		}
+		*numbers[i] = n
+	}
+	v.Parts = len(parts)
+	return v, nil
+}
+
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Pre != "" {
+		s += "-" + v.Pre
+	}
+	return s
+}
+
+// Compare orders versions by precedence; a pre-release sorts before the
+// release it leads up to.
+func (v Version) Compare(o Version) int {
+	for _, d := range []int{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		if d != 0 {
+			return sign(d)
+		}
+	}
+
+	switch {
+	case v.Pre == o.Pre:
+		return 0
+	case v.Pre == "":
+		return 1
+	case o.Pre == "":
+		return -1
+	}
+
+	a, b := strings.Split(v.Pre, "."), strings.Split(o.Pre, ".")
+	for i := 0; i < len(a) && i < len(b); i++ {
+		na, errA := strconv.Atoi(a[i])
+		nb, errB := strconv.Atoi(b[i])
+		switch {
+		case errA == nil && errB == nil:
+			if na != nb {
+				return sign(na - nb)
+			}
+		case errA == nil:
+			return -1
+		case errB == nil:
+			return 1
+		default:
+			if c := strings.Compare(a[i], b[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	return sign(len(a) - len(b))
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
+
+type comparator struct {
+	op string
+	v  Version
+}
+
+func (c comparator) matches(v Version) bool {
+	cmp := v.Compare(c.v)
+	switch c.op {
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	}
+	return cmp == 0
+}
+
+// Constraint is a version range in the npm/Cargo style:
+//
+//	^1.2       >=1.2.0 <2.0.0 (for 0.x the minor version is the breaking one)
+//	~1.2.3     >=1.2.3 <1.3.0
+//	1.2.x, 1.2 >=1.2.0 <1.3.0
+//	>=1.2 <2   comparators separated by spaces must all match
+//	^1 || ^2   either side may match
+//
+// Pre-releases only match when the constraint names a pre-release itself.
+type Constraint struct {
+	raw  string
+	sets [][]comparator
+	pre  bool
+}
+
+func ParseConstraint(s string) (*Constraint, error) {
+	c := &Constraint{raw: s, pre: strings.Contains(s, "-")}
+
+	for _, alternative := range strings.Split(s, "||") {
+		if strings.TrimSpace(alternative) == "" {
+			return nil, fmt.Errorf("invalid version range %q: empty alternative", s)
+		}
+
+		var set []comparator
+		for _, term := range strings.Fields(strings.ReplaceAll(alternative, ",", " ")) {
+			comparators, err := parseTerm(term)
+			if err != nil {
+				return nil, fmt.Errorf("invalid version range %q: %v", s, err)
+			}
+			set = append(set, comparators...)
+		}
+		c.sets = append(c.sets, set)
+	}
+	return c, nil
+}
+
+func parseTerm(term string) ([]comparator, error) {
+	op := ""
+	for _, candidate := range []string{">=", "<=", ">", "<", "=", "^", "~"} {
+		if strings.HasPrefix(term, candidate) {
+			op, term = candidate, strings.TrimPrefix(term, candidate)
+			break
+		}
+	}
+
+	// Wildcards turn into a partial version: 1.2.x is the same as 1.2.
+	var parts []string
+	for _, part := range strings.Split(strings.TrimPrefix(term, "v"), ".") {
+		if part == "x" || part == "X" || part == "*" {
+			break
+		}
+		parts = append(parts, part)
+	}
+	if len(parts) == 0 {
+		return nil, nil
+	}
+
+	v, err := ParseVersion(strings.Join(parts, "."))
+	if err != nil {
+		return nil, err
+	}
+
+	lower := comparator{">=", v}
+	switch op {
+	case ">", ">=", "<", "<=":
+		return []comparator{{op, v}}, nil
+	case "^":
+		upper := Version{Major: v.Major + 1}
+		switch {
+		case v.Major == 0 && v.Parts == 1:
+		case v.Major == 0 && (v.Minor > 0 || v.Parts == 2):
+			upper = Version{Minor: v.Minor + 1}
+		case v.Major == 0:
+			upper = Version{Minor: v.Minor, Patch: v.Patch + 1}
+		}
+		return []comparator{lower, {"<", upper}}, nil
+	case "~":
+		upper := Version{Major: v.Major + 1}
+		if v.Parts >= 2 {
+			upper = Version{Major: v.Major, Minor: v.Minor + 1}
+		}
+		return []comparator{lower, {"<", upper}}, nil
+	}
+
+	switch v.Parts {
+	case 1:
+		return []comparator{lower, {"<", Version{Major: v.Major + 1}}}, nil
+	case 2:
+		return []comparator{lower, {"<", Version{Major: v.Major, Minor: v.Minor + 1}}}, nil
+	}
+	return []comparator{{"=", v}}, nil
+}
+
+// Check reports whether v is inside the range.
+func (c *Constraint) Check(v Version) bool {
+	if v.Pre != "" && !c.pre {
+		return false
+	}
+
+	for _, set := range c.sets {
+		matched := true
+		for _, comp := range set {
+			if !comp.matches(v) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Constraint) String() string {
+	return c.raw
+}
+
+// IsRange tells version ranges apart from plain release tags, which are
+// looked up as they are.
+func IsRange(s string) bool {
+	if s == "*" || strings.ContainsAny(s, "^~<>=| ,") {
+		return true
+	}
+	for _, part := range strings.Split(s, ".") {
+		if part == "x" || part == "X" || part == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// Satisfies reports whether version is inside constraint. Unparsable input
+// never satisfies.
+func Satisfies(version, constraint string) bool {
+	v, err := ParseVersion(version)
+	if err != nil {
+		return false
+	}
+	c, err := ParseConstraint(constraint)
+	if err != nil {
+		return false
+	}
+	return c.Check(v)
+}
@@ -0,0 +1,24 @@
+package digest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "provider")
+	os.WriteFile(path, []byte("hello\n"), 0644)
+
+	sum, err := File(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum != "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03" {
+		t.Errorf("wrong digest: %s", sum)
+	}
+
+	if _, err := File(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
@@ -0,0 +1,205 @@
+package game
+
+import (
+	"time"
+
+	api "github.com/danielvollbro/gohl-api"
+)
+
+// Achievement is an unlocked badge. New marks badges unlocked by the scan
+// the report belongs to.
+type Achievement struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Icon        string    `json:"icon,omitempty"`
+	UnlockedAt  time.Time `json:"unlocked_at"`
+	New         bool      `json:"new,omitempty"`
+}
+
+// Badge is a rule that unlocks an achievement. Reports holds the scan
+// history, oldest first, ending with the current scan.
+type Badge struct {
+	ID          string
+	Name        string
+	Description string
+	Icon        string
+	Unlocked    func(reports []Report, now time.Time) bool
+}
+
+// StreakDays is how many consecutive days of scans the streak badge needs.
+const StreakDays = 7
+
+var Badges = []Badge{
+	{
+		ID:          "first-scan",
+		Name:        "Hello, Homelab",
+		Description: "Completed a first scan",
+		Icon:        "👋",
+		Unlocked: func(reports []Report, now time.Time) bool {
+			return true
+		},
+	},
+	{
+		ID:          "first-perfect",
+		Name:        "Flawless",
+		Description: "Reached a score of 100",
+		Icon:        "💯",
+		Unlocked: func(reports []Report, now time.Time) bool {
+			current := reports[len(reports)-1]
+			return current.MaxScore > 0 && percent(current) == 100
+		},
+	},
+	{
+		ID:          "streak-7",
+		Name:        "On a Roll",
+		Description: "Scanned every day for a week without a regression",
+		Icon:        "🔥",
+		Unlocked: func(reports []Report, now time.Time) bool {
+			return streak(reports, now) >= StreakDays
+		},
+	},
+	{
+		ID:          "first-fix",
+		Name:        "Quest Complete",
+		Description: "Fixed a failing check",
+		Icon:        "🔧",
+		Unlocked: func(reports []Report, now time.Time) bool {
+			return fixedChecks(reports) >= 1
+		},
+	},
+	{
+		ID:          "fixed-10",
+		Name:        "Troubleshooter",
+		Description: "Fixed 10 failing checks",
+		Icon:        "🛠️",
+		Unlocked: func(reports []Report, now time.Time) bool {
+			return fixedChecks(reports) >= 10
+		},
+	},
+	{
+		ID:          "containers-healthy",
+		Name:        "Shipshape",
+		Description: "Passed every Docker check",
+		Icon:        "🐳",
+		Unlocked: func(reports []Report, now time.Time) bool {
+			// A host without containers passes every check but earns nothing.
+			report := pluginReport(reports[len(reports)-1], "docker")
+			return report != nil && maxScore(report) > 0 && allPassed(report)
+		},
+	},
+}
+
+// UnlockAchievements evaluates the badges that are still locked and returns
+// every unlocked achievement, the new ones marked as such.
+func UnlockAchievements(current Report, history []Report, unlocked []Achievement, now time.Time) []Achievement {
+	have := make(map[string]bool, len(unlocked))
+	achievements := make([]Achievement, 0, len(unlocked))
+	for _, achievement := range unlocked {
+		have[achievement.ID] = true
+		achievement.New = false
+		achievements = append(achievements, achievement)
+	}
+
+	reports := append(history[:len(history):len(history)], current)
+	for _, badge := range Badges {
+		if have[badge.ID] || !badge.Unlocked(reports, now) {
+			continue
+		}
+		achievements = append(achievements, Achievement{
+			ID:          badge.ID,
+			Name:        badge.Name,
+			Description: badge.Description,
+			Icon:        badge.Icon,
+			UnlockedAt:  now,
+			New:         true,
+		})
+	}
+	return achievements
+}
+
+// percent is the report's 0-100 score. Reports saved before weighted
+// scoring only have the raw totals.
+func percent(r Report) int {
+	if len(r.Categories) > 0 || r.MaxScore == 0 {
+		return r.Score
+	}
+	return r.TotalScore * 100 / r.MaxScore
+}
+
+// streak counts the consecutive days, ending today, with at least one scan.
+// Days up to and including the last regression, a scan scoring lower than
+// the one before it, do not count.
+func streak(reports []Report, now time.Time) int {
+	day := func(t time.Time) string { return t.In(now.Location()).Format("2006-01-02") }
+
+	scanned := make(map[string]bool)
+	regressed := ""
+	for i, report := range reports {
+		at, err := time.Parse(time.RFC3339, report.Timestamp)
+		if err != nil {
+			continue
+		}
+		scanned[day(at)] = true
+		if i > 0 && percent(report) < percent(reports[i-1]) {
+			regressed = day(at)
+		}
+	}
+
+	count := 0
+	for d := now; scanned[day(d)] && day(d) > regressed; d = d.AddDate(0, 0, -1) {
+		count++
+	}
+	return count
+}
+
+// fixedChecks counts the distinct checks that passed in the scan after one
+// where they failed, so flipping one check back and forth counts once.
+func fixedChecks(reports []Report) int {
+	fixed := make(map[string]bool)
+	for i := 1; i < len(reports); i++ {
+		failing := make(map[string]bool)
+		for _, report := range reports[i-1].PluginReports {
+			for _, check := range report.Checks {
+				if !check.Passed {
+					failing[report.PluginID+"/"+check.ID] = true
+				}
+			}
+		}
+
+		for _, report := range reports[i].PluginReports {
+			for _, check := range report.Checks {
+				if check.Passed && failing[report.PluginID+"/"+check.ID] {
+					fixed[report.PluginID+"/"+check.ID] = true
+				}
+			}
+		}
+	}
+	return len(fixed)
+}
+
+func pluginReport(r Report, pluginID string) *api.ScanReport {
+	for _, report := range r.PluginReports {
+		if report != nil && report.PluginID == pluginID {
+			return report
+		}
+	}
+	return nil
+}
+
+func allPassed(report *api.ScanReport) bool {
+	for _, check := range report.Checks {
+		if !check.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+func maxScore(report *api.ScanReport) int {
+	total := 0
+	for _, check := range report.Checks {
+		total += check.MaxScore
+	}
+	return total
+}
@@ -0,0 +1,126 @@
+package game
+
+import (
+	"testing"
+	"time"
+
+	api "github.com/danielvollbro/gohl-api"
+)
+
+func scanAt(at time.Time, score int, checks ...api.CheckResult) Report {
+	return Report{
+		GrandReport: api.GrandReport{
+			Timestamp:     at.Format(time.RFC3339),
+			TotalScore:    score,
+			MaxScore:      100,
+			PluginReports: []*api.ScanReport{{PluginID: "system", Checks: checks}},
+		},
+		Score:      score,
+		Categories: []CategoryScore{{Name: DefaultCategory, Score: score}},
+	}
+}
+
+func ids(achievements []Achievement) map[string]bool {
+	found := make(map[string]bool)
+	for _, achievement := range achievements {
+		found[achievement.ID] = achievement.New
+	}
+	return found
+}
+
+func TestUnlockAchievements_FirstScan(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	current := scanAt(now, 40)
+
+	achievements := UnlockAchievements(current, nil, nil, now)
+	if found := ids(achievements); len(found) != 1 || !found["first-scan"] {
+		t.Fatalf("expected only the first scan badge, got %+v", achievements)
+	}
+	if !achievements[0].UnlockedAt.Equal(now) {
+		t.Errorf("unexpected unlock time %v", achievements[0].UnlockedAt)
+	}
+
+	again := UnlockAchievements(current, []Report{current}, achievements, now.Add(time.Hour))
+	if len(again) != 1 || again[0].New || !again[0].UnlockedAt.Equal(now) {
+		t.Errorf("unlocked badge should be kept as is, got %+v", again)
+	}
+}
+
+func TestUnlockAchievements_PerfectAndContainers(t *testing.T) {
+	now := time.Now()
+	current := scanAt(now, 100)
+	current.PluginReports = append(current.PluginReports, &api.ScanReport{
+		PluginID: "docker",
+		Checks:   []api.CheckResult{{ID: "docker-healthcheck", Passed: true, Score: 5, MaxScore: 5}},
+	})
+
+	found := ids(UnlockAchievements(current, nil, nil, now))
+	if !found["first-perfect"] || !found["containers-healthy"] {
+		t.Errorf("expected perfect and docker badges, got %v", found)
+	}
+
+	empty := scanAt(now, 100)
+	empty.PluginReports = append(empty.PluginReports, &api.ScanReport{
+		PluginID: "docker",
+		Checks:   []api.CheckResult{{ID: "docker-healthcheck", Passed: true, Description: "No running containers to check"}},
+	})
+	if found := ids(UnlockAchievements(empty, nil, nil, now)); found["containers-healthy"] {
+		t.Error("the docker badge should not unlock on a host without containers")
+	}
+}
+
+func TestUnlockAchievements_Streak(t *testing.T) {
+	now := time.Date(2026, 3, 10, 20, 0, 0, 0, time.UTC)
+
+	var history []Report
+	for day := StreakDays - 1; day >= 1; day-- {
+		history = append(history, scanAt(now.AddDate(0, 0, -day), 50))
+	}
+
+	if found := ids(UnlockAchievements(scanAt(now, 60), history, nil, now)); !found["streak-7"] {
+		t.Errorf("expected a streak after %d days, got %v", StreakDays, found)
+	}
+
+	// A regression three days ago resets the streak.
+	history[3].Score = 10
+	history[3].Categories[0].Score = 10
+	if found := ids(UnlockAchievements(scanAt(now, 60), history, nil, now)); found["streak-7"] {
+		t.Error("streak should not survive a regression")
+	}
+
+	// So does a missed day.
+	if found := ids(UnlockAchievements(scanAt(now, 60), history[:2], nil, now)); found["streak-7"] {
+		t.Error("streak should not survive a gap")
+	}
+}
+
+func TestUnlockAchievements_FixedChecks(t *testing.T) {
+	now := time.Now()
+
+	checks := func(passed bool, ids ...string) []api.CheckResult {
+		var results []api.CheckResult
+		for _, id := range ids {
+			results = append(results, api.CheckResult{ID: id, Passed: passed, MaxScore: 1})
+		}
+		return results
+	}
+	first := []string{"a", "b", "c", "d", "e"}
+	second := []string{"f", "g", "h", "i", "j"}
+
+	// The same five checks fixed twice are five fixes, not ten.
+	history := []Report{
+		scanAt(now, 0, checks(false, first...)...),
+		scanAt(now, 100, checks(true, first...)...),
+		scanAt(now, 0, checks(false, first...)...),
+	}
+	found := ids(UnlockAchievements(scanAt(now, 100, checks(true, first...)...), history, nil, now))
+	if !found["first-fix"] || found["fixed-10"] {
+		t.Errorf("expected only the first fix badge, got %v", found)
+	}
+
+	history = append(history, scanAt(now, 100, checks(true, first...)...), scanAt(now, 0, checks(false, second...)...))
+	found = ids(UnlockAchievements(scanAt(now, 100, checks(true, second...)...), history, nil, now))
+	if !found["fixed-10"] {
+		t.Errorf("expected the fixed-10 badge after 10 distinct fixes, got %v", found)
+	}
+}
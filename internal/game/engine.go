@@ -7,7 +7,41 @@ import (
 	api "github.com/danielvollbro/gohl-api"
 )
 
-func CompileReport(reports []*api.ScanReport, labID string) api.GrandReport {
+// Report is the agent's view of a scan: the shared api.GrandReport plus
+// agent-side details. Embedding keeps the JSON flat, so consumers of the
+// plain GrandReport can still decode it.
+type Report struct {
+	api.GrandReport
+	Providers []ProviderRun `json:"providers,omitempty"`
+	Warnings  []Warning     `json:"warnings,omitempty"`
+
+	// Score is the weighted 0-100 score, see Scoring, and the value ranks
+	// and leaderboards are based on. TotalScore and MaxScore keep the plain
+	// sums of the provider points and are not comparable across labs.
+	Score      int             `json:"score"`
+	Categories []CategoryScore `json:"categories,omitempty"`
+
+	// RankID identifies the rank independent of the ladder's display
+	// names, which end up in Rank.
+	RankID    string `json:"rank_id"`
+	RankColor string `json:"rank_color,omitempty"`
+
+	Achievements []Achievement `json:"achievements,omitempty"`
+	Quests       *QuestUpdate  `json:"quests,omitempty"`
+	XP           *Progress     `json:"xp,omitempty"`
+}
+
+// ProviderRun records which provider build produced a report.
+type ProviderRun struct {
+	Name     string         `json:"name"`
+	Plugin   api.PluginInfo `json:"plugin"`
+	Protocol int            `json:"protocol,omitempty"`
+	Duration string         `json:"duration,omitempty"`
+	Error    string         `json:"error,omitempty"`
+	Partial  bool           `json:"partial,omitempty"`
+}
+
+func CompileReport(reports []*api.ScanReport, labID string, scoring Scoring, ladder Ladder) Report {
 	var totalScore, maxScore int
 
 	for _, report := range reports {
@@ -18,14 +52,27 @@ func CompileReport(reports []*api.ScanReport, labID string) api.GrandReport {
 	}
 
 	hostname, _ := os.Hostname()
-	rankName, _ := GetRank(totalScore, maxScore)
-	return api.GrandReport{
-		LabID:         labID,
-		Hostname:      hostname,
-		Timestamp:     time.Now().Format(time.RFC3339),
-		TotalScore:    totalScore,
-		MaxScore:      maxScore,
-		Rank:          rankName,
-		PluginReports: reports,
+	score, categories := scoring.Score(reports)
+
+	// Reports without any points to earn stay unranked.
+	rank := Unranked
+	if maxScore > 0 {
+		rank = ladder.Rank(score, categories)
+	}
+
+	return Report{
+		GrandReport: api.GrandReport{
+			LabID:         labID,
+			Hostname:      hostname,
+			Timestamp:     time.Now().Format(time.RFC3339),
+			TotalScore:    totalScore,
+			MaxScore:      maxScore,
+			Rank:          rank.Title(),
+			PluginReports: reports,
+		},
+		RankID:     rank.ID,
+		RankColor:  rank.Color,
+		Score:      score,
+		Categories: categories,
 	}
 }
@@ -0,0 +1,179 @@
+package game
+
+import (
+	"sort"
+	"time"
+
+	api "github.com/danielvollbro/gohl-api"
+)
+
+type QuestStatus string
+
+const (
+	QuestOpen      QuestStatus = "open"
+	QuestCompleted QuestStatus = "completed"
+	QuestRegressed QuestStatus = "regressed"
+	QuestRetired   QuestStatus = "retired"
+)
+
+// Quest follows a failing check across scans. A quest is completed when the
+// check passes and regressed, which counts as open again, when it fails
+// after that. An open quest is retired when its provider ran to completion
+// without reporting the check, e.g. because the check was removed. Checks
+// missing from a failed or partial run, and checks that could not be
+// evaluated, leave their quest as it is.
+type Quest struct {
+	ID          string      `json:"id"`
+	PluginID    string      `json:"plugin_id"`
+	CheckID     string      `json:"check_id"`
+	Name        string      `json:"name"`
+	Remediation string      `json:"remediation,omitempty"`
+	Status      QuestStatus `json:"status"`
+
+	// Impact is the weighted points the check was missing when it last
+	// failed, see Scoring.
+	Impact float64 `json:"impact"`
+
+	FirstSeen time.Time `json:"first_seen"`
+	OpenedAt  time.Time `json:"opened_at"`
+
+	// CompletedAt is when the quest was completed or retired.
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	Regressions int        `json:"regressions,omitempty"`
+}
+
+func (q Quest) IsOpen() bool {
+	return q.Status != QuestCompleted && q.Status != QuestRetired
+}
+
+// OpenFor is how long the quest has been open since it was last opened.
+func (q Quest) OpenFor(now time.Time) time.Duration {
+	if !q.IsOpen() && q.CompletedAt != nil {
+		return q.CompletedAt.Sub(q.OpenedAt)
+	}
+	return now.Sub(q.OpenedAt)
+}
+
+// QuestUpdate lists the quests whose state a scan changed.
+type QuestUpdate struct {
+	Opened    []Quest `json:"opened,omitempty"`
+	Completed []Quest `json:"completed,omitempty"`
+	Regressed []Quest `json:"regressed,omitempty"`
+	Retired   []Quest `json:"retired,omitempty"`
+}
+
+func questID(pluginID, checkID string) string {
+	return pluginID + "/" + checkID
+}
+
+// UpdateQuests applies a scan to the quest log and returns the new log,
+// sorted with SortQuests, along with what changed. Complete holds the plugin
+// IDs whose run finished without an error; only their missing checks retire
+// quests.
+func UpdateQuests(quests []Quest, reports []*api.ScanReport, complete map[string]bool, scoring Scoring, now time.Time) ([]Quest, QuestUpdate) {
+	byID := make(map[string]*Quest, len(quests))
+	log := make([]*Quest, 0, len(quests))
+	for i := range quests {
+		quest := quests[i]
+		byID[quest.ID] = &quest
+		log = append(log, &quest)
+	}
+
+	var update QuestUpdate
+	var opened, regressed []*Quest
+	reported := make(map[string]bool)
+	for _, report := range reports {
+		providerWeight := scoring.weight(scoring.Providers, report.PluginID)
+
+		for _, check := range report.Checks {
+			id := questID(report.PluginID, check.ID)
+			reported[id] = true
+			quest, known := byID[id]
+
+			// A check that could not be evaluated says nothing either way.
+			if check.Error != "" {
+				continue
+			}
+
+			if check.Passed {
+				if known && quest.IsOpen() {
+					completedAt := now
+					quest.Status = QuestCompleted
+					quest.CompletedAt = &completedAt
+					update.Completed = append(update.Completed, *quest)
+				}
+				continue
+			}
+
+			multiplier, ok := scoring.multiplier(scoring.Rule(check.ID).Severity)
+			if !ok {
+				multiplier = DefaultSeverities[DefaultSeverity]
+			}
+
+			switch {
+			case !known:
+				quest = &Quest{ID: id, PluginID: report.PluginID, CheckID: check.ID, Status: QuestOpen, FirstSeen: now, OpenedAt: now}
+				byID[id] = quest
+				log = append(log, quest)
+				opened = append(opened, quest)
+			case quest.Status == QuestRetired:
+				quest.Status = QuestOpen
+				quest.OpenedAt = now
+				quest.CompletedAt = nil
+				opened = append(opened, quest)
+			case !quest.IsOpen():
+				quest.Status = QuestRegressed
+				quest.OpenedAt = now
+				quest.CompletedAt = nil
+				quest.Regressions++
+				regressed = append(regressed, quest)
+			}
+
+			quest.Name = check.Name
+			if quest.Name == "" {
+				quest.Name = check.ID
+			}
+			quest.Remediation = check.Remediation
+			quest.Impact = providerWeight * multiplier * float64(check.MaxScore-check.Score)
+		}
+	}
+
+	for _, quest := range log {
+		if quest.IsOpen() && complete[quest.PluginID] && !reported[quest.ID] {
+			retiredAt := now
+			quest.Status = QuestRetired
+			quest.CompletedAt = &retiredAt
+			update.Retired = append(update.Retired, *quest)
+		}
+	}
+
+	// Opened and regressed quests are copied once their details are filled in.
+	for _, quest := range opened {
+		update.Opened = append(update.Opened, *quest)
+	}
+	for _, quest := range regressed {
+		update.Regressed = append(update.Regressed, *quest)
+	}
+
+	result := make([]Quest, len(log))
+	for i, quest := range log {
+		result[i] = *quest
+	}
+	SortQuests(result)
+	return result, update
+}
+
+// SortQuests puts open quests first, then orders by impact, largest first,
+// and finally by ID.
+func SortQuests(quests []Quest) {
+	sort.SliceStable(quests, func(i, j int) bool {
+		a, b := quests[i], quests[j]
+		if a.IsOpen() != b.IsOpen() {
+			return a.IsOpen()
+		}
+		if a.Impact != b.Impact {
+			return a.Impact > b.Impact
+		}
+		return a.ID < b.ID
+	})
+}
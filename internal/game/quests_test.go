@@ -0,0 +1,137 @@
+package game
+
+import (
+	"testing"
+	"time"
+
+	api "github.com/danielvollbro/gohl-api"
+)
+
+func questScan(checks ...api.CheckResult) []*api.ScanReport {
+	return []*api.ScanReport{{PluginID: "system", Checks: checks}}
+}
+
+func TestUpdateQuests_Lifecycle(t *testing.T) {
+	start := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	failing := api.CheckResult{ID: "sys-swap", Name: "Swap Configured", Remediation: "Add swap", MaxScore: 5}
+	passing := api.CheckResult{ID: "sys-swap", Name: "Swap Configured", Passed: true, Score: 5, MaxScore: 5}
+
+	quests, update := UpdateQuests(nil, questScan(failing), nil, Scoring{}, start)
+	if len(quests) != 1 || len(update.Opened) != 1 {
+		t.Fatalf("expected a new quest, got %+v %+v", quests, update)
+	}
+	quest := quests[0]
+	if quest.ID != "system/sys-swap" || quest.Status != QuestOpen || quest.Impact != 10 || !quest.FirstSeen.Equal(start) {
+		t.Errorf("unexpected quest: %+v", quest)
+	}
+	if update.Opened[0].Name != "Swap Configured" {
+		t.Errorf("opened quest is missing details: %+v", update.Opened[0])
+	}
+
+	// Still failing: nothing changes, the quest ages.
+	later := start.Add(48 * time.Hour)
+	quests, update = UpdateQuests(quests, questScan(failing), nil, Scoring{}, later)
+	if len(update.Opened)+len(update.Completed)+len(update.Regressed) != 0 {
+		t.Errorf("expected no changes, got %+v", update)
+	}
+	if quests[0].OpenFor(later) != 48*time.Hour {
+		t.Errorf("unexpected age %v", quests[0].OpenFor(later))
+	}
+
+	// A provider that did not report leaves quests alone.
+	quests, _ = UpdateQuests(quests, nil, nil, Scoring{}, later)
+	if !quests[0].IsOpen() {
+		t.Fatal("missing check must not complete the quest")
+	}
+
+	fixed := later.Add(time.Hour)
+	quests, update = UpdateQuests(quests, questScan(passing), nil, Scoring{}, fixed)
+	if len(update.Completed) != 1 || quests[0].Status != QuestCompleted || !quests[0].CompletedAt.Equal(fixed) {
+		t.Fatalf("expected the quest to be completed, got %+v", quests[0])
+	}
+	if quests[0].OpenFor(fixed.Add(time.Hour)) != 49*time.Hour {
+		t.Errorf("completed quest should keep its open time, got %v", quests[0].OpenFor(fixed))
+	}
+
+	broken := fixed.Add(24 * time.Hour)
+	quests, update = UpdateQuests(quests, questScan(failing), nil, Scoring{}, broken)
+	if len(update.Regressed) != 1 {
+		t.Fatalf("expected a regression, got %+v", update)
+	}
+	quest = quests[0]
+	if quest.Status != QuestRegressed || !quest.IsOpen() || quest.CompletedAt != nil || quest.Regressions != 1 || !quest.OpenedAt.Equal(broken) || !quest.FirstSeen.Equal(start) {
+		t.Errorf("unexpected regressed quest: %+v", quest)
+	}
+}
+
+func TestUpdateQuests_SortedByImpact(t *testing.T) {
+	scoring := Scoring{Checks: map[string]CheckRule{"sys-auto-updates": {Severity: "critical"}}}
+	quests, _ := UpdateQuests(nil, questScan(
+		api.CheckResult{ID: "sys-swap", MaxScore: 10},
+		api.CheckResult{ID: "sys-auto-updates", MaxScore: 5},
+		api.CheckResult{ID: "sys-uptime", Passed: true, Score: 5, MaxScore: 5},
+		api.CheckResult{ID: "sys-disk", Score: 2, MaxScore: 5},
+	), nil, scoring, time.Now())
+
+	want := []string{"sys-auto-updates", "sys-swap", "sys-disk"}
+	if len(quests) != len(want) {
+		t.Fatalf("unexpected quests: %+v", quests)
+	}
+	for i, id := range want {
+		if quests[i].CheckID != id {
+			t.Errorf("position %d: got %s, want %s", i, quests[i].CheckID, id)
+		}
+	}
+	if quests[2].Name != "sys-disk" {
+		t.Errorf("quest without a check name should use the id, got %q", quests[2].Name)
+	}
+}
+
+func TestUpdateQuests_Retired(t *testing.T) {
+	start := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	complete := map[string]bool{"system": true}
+	swap := api.CheckResult{ID: "sys-swap", MaxScore: 5}
+	disk := api.CheckResult{ID: "sys-disk", MaxScore: 5}
+
+	quests, _ := UpdateQuests(nil, questScan(swap, disk), complete, Scoring{}, start)
+
+	// A partial run without sys-disk leaves the quest open.
+	later := start.Add(time.Hour)
+	quests, update := UpdateQuests(quests, questScan(swap), nil, Scoring{}, later)
+	if len(update.Retired) != 0 {
+		t.Fatalf("partial run retired quests: %+v", update.Retired)
+	}
+
+	// A check that errored is neither failing nor gone.
+	errored := api.CheckResult{ID: "sys-disk", MaxScore: 5, Error: "permission denied"}
+	quests, update = UpdateQuests(quests, questScan(swap, errored), complete, Scoring{}, later)
+	if len(update.Retired)+len(update.Completed)+len(update.Opened) != 0 {
+		t.Fatalf("errored check changed the quest log: %+v", update)
+	}
+
+	// A complete run that no longer reports sys-disk retires its quest.
+	quests, update = UpdateQuests(quests, questScan(swap), complete, Scoring{}, later)
+	if len(update.Retired) != 1 || update.Retired[0].CheckID != "sys-disk" {
+		t.Fatalf("expected sys-disk to be retired, got %+v", update)
+	}
+	var retired Quest
+	for _, quest := range quests {
+		if quest.CheckID == "sys-disk" {
+			retired = quest
+		}
+	}
+	if retired.Status != QuestRetired || retired.IsOpen() || !retired.CompletedAt.Equal(later) {
+		t.Errorf("unexpected retired quest: %+v", retired)
+	}
+
+	// Coming back failing reopens it without counting a regression.
+	quests, update = UpdateQuests(quests, questScan(swap, disk), complete, Scoring{}, later.Add(time.Hour))
+	if len(update.Opened) != 1 || len(update.Regressed) != 0 {
+		t.Errorf("expected the quest to reopen, got %+v", update)
+	}
+	for _, quest := range quests {
+		if quest.CheckID == "sys-disk" && (quest.Status != QuestOpen || quest.Regressions != 0) {
+			t.Errorf("unexpected reopened quest: %+v", quest)
+		}
+	}
+}
@@ -1,26 +1,148 @@
 package game
 
-import "github.com/pterm/pterm"
-
-func GetRank(score, maxScore int) (string, pterm.Color) {
-	if maxScore == 0 {
-		return "Unranked", pterm.FgGray
-	}
-
-	percentage := float64(score) / float64(maxScore) * 100
-
-	switch {
-	case percentage == 100:
-		return "HOMELAB GOD ⚡", pterm.FgLightMagenta
-	case percentage >= 90:
-		return "System Architect 🏗️", pterm.FgCyan
-	case percentage >= 75:
-		return "DevOps Engineer 🚀", pterm.FgGreen
-	case percentage >= 50:
-		return "Junior Sysadmin 🛠️", pterm.FgYellow
-	case percentage >= 25:
-		return "Script Kiddie 💻", pterm.FgRed
+import (
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// Rank is one step of a rank ladder. ID is what the server and JSON
+// consumers should key on; Name, Icon and Color are presentation and may be
+// changed by a theme without affecting stored history.
+type Rank struct {
+	ID       string           `json:"id" mapstructure:"id"`
+	Name     string           `json:"name" mapstructure:"name"`
+	Icon     string           `json:"icon,omitempty" mapstructure:"icon"`
+	Color    string           `json:"color,omitempty" mapstructure:"color"`
+	MinScore int              `json:"min_score" mapstructure:"min_score"`
+	Requires RankRequirements `json:"requires,omitempty" mapstructure:"requires"`
+}
+
+// RankRequirements break ties between ranks reached by the same score. A
+// rank whose requirements are not met is skipped for the next lower one. A
+// category without any scored checks does not meet its minimum.
+type RankRequirements struct {
+	NoCriticalFailures bool           `json:"no_critical_failures,omitempty" mapstructure:"no_critical_failures"`
+	MaxFailed          *int           `json:"max_failed,omitempty" mapstructure:"max_failed"`
+	Categories         map[string]int `json:"categories,omitempty" mapstructure:"categories"`
+}
+
+// Title is the rank as shown to players and stored in GrandReport.Rank.
+func (r Rank) Title() string {
+	if r.Icon == "" {
+		return r.Name
+	}
+	return r.Name + " " + r.Icon
+}
+
+// Unranked is given to reports without any points to earn.
+var Unranked = Rank{ID: "unranked", Name: "Unranked", Color: "gray"}
+
+// Ladder lists ranks in any order; Rank walks them from the highest
+// min_score down.
+type Ladder []Rank
+
+func DefaultLadder() Ladder {
+	return Ladder{
+		{ID: "homelab-god", Name: "HOMELAB GOD", Icon: "⚡", Color: "light-magenta", MinScore: 100},
+		{ID: "system-architect", Name: "System Architect", Icon: "🏗️", Color: "cyan", MinScore: 90},
+		{ID: "devops-engineer", Name: "DevOps Engineer", Icon: "🚀", Color: "green", MinScore: 75},
+		{ID: "junior-sysadmin", Name: "Junior Sysadmin", Icon: "🛠️", Color: "yellow", MinScore: 50},
+		{ID: "script-kiddie", Name: "Script Kiddie", Icon: "💻", Color: "red", MinScore: 25},
+		{ID: "intern", Name: "Intern checking logs", Icon: "📄", Color: "gray", MinScore: 0},
+	}
+}
+
+var rankID = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// isFloor reports whether every report reaches the rank: min_score 0 and no
+// requirements.
+func (r Rank) isFloor() bool {
+	return r.MinScore == 0 && !r.Requires.NoCriticalFailures && r.Requires.MaxFailed == nil && len(r.Requires.Categories) == 0
+}
+
+// Validate checks that IDs are unique slugs, thresholds are on the 0-100
+// score scale and exactly one floor rank catches every score.
+func (l Ladder) Validate() error {
+	if len(l) == 0 {
+		return fmt.Errorf("rank ladder is empty")
+	}
+
+	seen := make(map[string]bool, len(l))
+	var floors []string
+	for _, rank := range l {
+		switch {
+		case !rankID.MatchString(rank.ID):
+			return fmt.Errorf("rank '%s': id must be a lowercase slug", rank.ID)
+		case rank.ID == Unranked.ID || seen[rank.ID]:
+			return fmt.Errorf("rank id '%s' is used twice", rank.ID)
+		case rank.Name == "":
+			return fmt.Errorf("rank '%s' has no name", rank.ID)
+		case rank.MinScore < 0 || rank.MinScore > 100:
+			return fmt.Errorf("rank '%s': min_score must be between 0 and 100", rank.ID)
+		case rank.Requires.MaxFailed != nil && *rank.Requires.MaxFailed < 0:
+			return fmt.Errorf("rank '%s': max_failed must not be negative", rank.ID)
+		}
+		seen[rank.ID] = true
+		if rank.isFloor() {
+			floors = append(floors, rank.ID)
+		}
+	}
+
+	switch len(floors) {
+	case 0:
+		return fmt.Errorf("rank ladder needs a floor rank with min_score 0 and no requirements")
+	case 1:
+		return nil
 	default:
-		return "Intern checking logs 📄", pterm.FgGray
+		return fmt.Errorf("rank ladder has %d floor ranks (%v), it needs exactly one", len(floors), floors)
+	}
+}
+
+// Rank returns the highest rank the score reaches whose requirements are
+// met. Ranks with the same min_score are tried in ladder order. The floor
+// rank is the fallback when nothing else fits, Unranked if there is none.
+func (l Ladder) Rank(score int, categories []CategoryScore) Rank {
+	if len(l) == 0 {
+		return Unranked
+	}
+
+	ranks := make(Ladder, len(l))
+	copy(ranks, l)
+	sort.SliceStable(ranks, func(i, j int) bool { return ranks[i].MinScore > ranks[j].MinScore })
+
+	for _, rank := range ranks {
+		if score >= rank.MinScore && rank.Requires.met(categories) {
+			return rank
+		}
+	}
+	for _, rank := range ranks {
+		if rank.isFloor() {
+			return rank
+		}
+	}
+	return Unranked
+}
+
+func (r RankRequirements) met(categories []CategoryScore) bool {
+	failed, critical := 0, 0
+	subscores := make(map[string]int, len(categories))
+	for _, category := range categories {
+		failed += category.Failed
+		critical += category.Critical
+		subscores[category.Name] = category.Score
+	}
+
+	if r.NoCriticalFailures && critical > 0 {
+		return false
+	}
+	if r.MaxFailed != nil && failed > *r.MaxFailed {
+		return false
+	}
+	for name, minimum := range r.Categories {
+		if score, ok := subscores[name]; !ok || score < minimum {
+			return false
+		}
 	}
-}
\ No newline at end of file
+	return true
+}
@@ -0,0 +1,115 @@
+package game
+
+import (
+	"testing"
+
+	api "github.com/danielvollbro/gohl-api"
+)
+
+func TestDefaultLadder(t *testing.T) {
+	ladder := DefaultLadder()
+	if err := ladder.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[int]string{
+		100: "homelab-god",
+		99:  "system-architect",
+		75:  "devops-engineer",
+		50:  "junior-sysadmin",
+		30:  "script-kiddie",
+		0:   "intern",
+	}
+	for score, want := range cases {
+		if got := ladder.Rank(score, nil); got.ID != want {
+			t.Errorf("score %d: got %s, want %s", score, got.ID, want)
+		}
+	}
+
+	if title := ladder.Rank(100, nil).Title(); title != "HOMELAB GOD ⚡" {
+		t.Errorf("unexpected title %q", title)
+	}
+}
+
+func TestLadder_TieBreaks(t *testing.T) {
+	none := 0
+	ladder := Ladder{
+		{ID: "bronze", Name: "Bronze", MinScore: 0},
+		{ID: "gold", Name: "Gold", MinScore: 80, Requires: RankRequirements{NoCriticalFailures: true}},
+		{ID: "gold-probation", Name: "Gold (probation)", MinScore: 80},
+		{ID: "flawless", Name: "Flawless", MinScore: 80, Requires: RankRequirements{MaxFailed: &none, Categories: map[string]int{"security": 100}}},
+	}
+	if err := ladder.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	clean := []CategoryScore{{Name: "security", Score: 100}, {Name: "general", Score: 70, Failed: 1}}
+	if got := ladder.Rank(85, clean); got.ID != "gold" {
+		t.Errorf("expected gold without critical failures, got %s", got.ID)
+	}
+
+	critical := []CategoryScore{{Name: "security", Score: 90, Failed: 1, Critical: 1}}
+	if got := ladder.Rank(85, critical); got.ID != "gold-probation" {
+		t.Errorf("expected the tie-break to skip gold, got %s", got.ID)
+	}
+
+	perfect := []CategoryScore{{Name: "security", Score: 100}}
+	if got := ladder.Rank(80, perfect); got.ID != "gold" {
+		t.Errorf("ranks with equal thresholds should keep ladder order, got %s", got.ID)
+	}
+
+	unscored := []CategoryScore{{Name: "general", Score: 100}}
+	if got := ladder.Rank(100, unscored); got.ID != "gold" {
+		t.Errorf("a category without scored checks should not meet its minimum, got %s", got.ID)
+	}
+
+	if got := ladder.Rank(10, critical); got.ID != "bronze" {
+		t.Errorf("expected the lowest rank, got %s", got.ID)
+	}
+
+	if got := ladder.Rank(-5, critical); got.ID != "bronze" {
+		t.Errorf("expected the floor rank as fallback, got %s", got.ID)
+	}
+}
+
+func TestLadder_Validate(t *testing.T) {
+	invalid := []Ladder{
+		{},
+		{{ID: "Gold", Name: "Gold"}},
+		{{ID: "gold", Name: "Gold"}, {ID: "gold", Name: "Also gold"}},
+		{{ID: "unranked", Name: "Nothing"}},
+		{{ID: "gold", Name: ""}},
+		{{ID: "gold", Name: "Gold", MinScore: 101}},
+		{{ID: "gold", Name: "Gold", MinScore: 50}},
+		{{ID: "strict", Name: "Strict", Requires: RankRequirements{NoCriticalFailures: true}}},
+		{{ID: "bronze", Name: "Bronze"}, {ID: "tin", Name: "Tin"}},
+	}
+	for _, ladder := range invalid {
+		if err := ladder.Validate(); err == nil {
+			t.Errorf("expected error for %+v", ladder)
+		}
+	}
+
+	// The floor is the fallback even when it is not the last rank.
+	ladder := Ladder{
+		{ID: "floor", Name: "Floor"},
+		{ID: "clean", Name: "Clean", Requires: RankRequirements{NoCriticalFailures: true}},
+	}
+	if err := ladder.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	critical := []CategoryScore{{Name: "security", Critical: 1}}
+	if got := ladder.Rank(-1, critical); got.ID != "floor" {
+		t.Errorf("expected the floor rank, got %s", got.ID)
+	}
+}
+
+func TestCompileReport_RankID(t *testing.T) {
+	reports := []*api.ScanReport{{PluginID: "system", Checks: []api.CheckResult{{ID: "a", Passed: true, Score: 1, MaxScore: 1}}}}
+	ladder := Ladder{{ID: "champ", Name: "Champion", Icon: "🏆", Color: "green", MinScore: 0}}
+
+	report := CompileReport(reports, "lab", Scoring{}, ladder)
+	if report.RankID != "champ" || report.Rank != "Champion 🏆" || report.RankColor != "green" {
+		t.Errorf("unexpected rank: %s %q %s", report.RankID, report.Rank, report.RankColor)
+	}
+}
@@ -0,0 +1,224 @@
+package game
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"sort"
+	"strings"
+
+	api "github.com/danielvollbro/gohl-api"
+)
+
+// DefaultCategory holds checks that no scoring rule assigns elsewhere.
+const DefaultCategory = "general"
+
+// DefaultSeverity is used for checks without a severity rule.
+const DefaultSeverity = "medium"
+
+// DefaultSeverities are the multipliers applied to a check's points.
+var DefaultSeverities = map[string]float64{
+	"critical": 8,
+	"high":     4,
+	"medium":   2,
+	"low":      1,
+}
+
+// Scoring is the 'scoring' section of gohl.yaml. Weights default to 1.
+//
+// Every check is worth its max_score times the weight of its provider and
+// the multiplier of its severity. Categories are scored on their own and
+// the final score is the weighted average of the category subscores, so a
+// provider with many trivial checks cannot outweigh a few critical ones in
+// another category.
+type Scoring struct {
+	// Providers weights checks by the plugin that reported them.
+	Providers map[string]float64 `mapstructure:"providers"`
+
+	// Categories weights the category subscores in the final score.
+	Categories map[string]float64 `mapstructure:"categories"`
+
+	// Severities overrides or extends DefaultSeverities.
+	Severities map[string]float64 `mapstructure:"severities"`
+
+	// Checks assigns categories and severities by check ID. Keys may be
+	// glob patterns such as "docker-*"; an exact ID wins over patterns and
+	// longer patterns win over shorter ones.
+	Checks map[string]CheckRule `mapstructure:"checks"`
+}
+
+type CheckRule struct {
+	Category string `mapstructure:"category"`
+	Severity string `mapstructure:"severity"`
+}
+
+// CategoryScore is one category's share of the final score.
+type CategoryScore struct {
+	Name     string  `json:"name"`
+	Score    int     `json:"score"`
+	Weight   float64 `json:"weight"`
+	Checks   int     `json:"checks"`
+	Failed   int     `json:"failed"`
+	Critical int     `json:"critical_failed,omitempty"`
+}
+
+// Validate rejects negative weights and rules naming unknown severities.
+func (s Scoring) Validate() error {
+	for _, weights := range []map[string]float64{s.Providers, s.Categories, s.Severities} {
+		for name, weight := range weights {
+			if weight < 0 || math.IsNaN(weight) {
+				return fmt.Errorf("weight of '%s' must not be negative", name)
+			}
+		}
+	}
+
+	for pattern, rule := range s.Checks {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid check pattern '%s': %v", pattern, err)
+		}
+		if rule.Severity != "" {
+			if _, ok := s.multiplier(rule.Severity); !ok {
+				return fmt.Errorf("check '%s': unknown severity '%s'", pattern, rule.Severity)
+			}
+		}
+	}
+	return nil
+}
+
+// AliasProvider lets a weight configured under the gohl.yaml provider name
+// apply to the plugin ID its reports carry.
+func (s *Scoring) AliasProvider(name, pluginID string) {
+	if name == pluginID {
+		return
+	}
+	weight, ok := lookup(s.Providers, name)
+	if !ok {
+		return
+	}
+	if _, exists := lookup(s.Providers, pluginID); !exists {
+		s.Providers[strings.ToLower(pluginID)] = weight
+	}
+}
+
+// Rule returns the category and severity a check is scored with.
+func (s Scoring) Rule(checkID string) CheckRule {
+	id := strings.ToLower(checkID)
+
+	rule, ok := s.Checks[id]
+	if !ok {
+		patterns := make([]string, 0, len(s.Checks))
+		for pattern := range s.Checks {
+			patterns = append(patterns, pattern)
+		}
+		sort.Slice(patterns, func(i, j int) bool {
+			if len(patterns[i]) != len(patterns[j]) {
+				return len(patterns[i]) > len(patterns[j])
+			}
+			return patterns[i] < patterns[j]
+		})
+
+		for _, pattern := range patterns {
+			if matched, _ := path.Match(strings.ToLower(pattern), id); matched {
+				rule = s.Checks[pattern]
+				break
+			}
+		}
+	}
+
+	if rule.Category == "" {
+		rule.Category = DefaultCategory
+	}
+	if rule.Severity == "" {
+		rule.Severity = DefaultSeverity
+	}
+	rule.Category = strings.ToLower(rule.Category)
+	rule.Severity = strings.ToLower(rule.Severity)
+	return rule
+}
+
+func (s Scoring) multiplier(severity string) (float64, bool) {
+	if m, ok := lookup(s.Severities, severity); ok {
+		return m, true
+	}
+	m, ok := DefaultSeverities[strings.ToLower(severity)]
+	return m, ok
+}
+
+func (s Scoring) weight(weights map[string]float64, name string) float64 {
+	if w, ok := lookup(weights, name); ok {
+		return w
+	}
+	return 1
+}
+
+// lookup is case-insensitive because viper lowercases config keys.
+func lookup(weights map[string]float64, name string) (float64, bool) {
+	w, ok := weights[strings.ToLower(name)]
+	return w, ok
+}
+
+// Score computes the normalized 0-100 score and the category subscores.
+// Scores are rounded down, so 100 means nothing was missed. Categories
+// without any points to earn are listed but do not count.
+func (s Scoring) Score(reports []*api.ScanReport) (int, []CategoryScore) {
+	type tally struct {
+		CategoryScore
+		earned, possible float64
+	}
+	tallies := make(map[string]*tally)
+
+	for _, report := range reports {
+		providerWeight := s.weight(s.Providers, report.PluginID)
+
+		for _, check := range report.Checks {
+			rule := s.Rule(check.ID)
+			multiplier, ok := s.multiplier(rule.Severity)
+			if !ok {
+				multiplier = DefaultSeverities[DefaultSeverity]
+			}
+
+			t, ok := tallies[rule.Category]
+			if !ok {
+				t = &tally{CategoryScore: CategoryScore{Name: rule.Category, Weight: s.weight(s.Categories, rule.Category)}}
+				tallies[rule.Category] = t
+			}
+
+			t.Checks++
+			if !check.Passed {
+				t.Failed++
+				if rule.Severity == "critical" {
+					t.Critical++
+				}
+			}
+
+			w := providerWeight * multiplier
+			t.earned += w * float64(check.Score)
+			t.possible += w * float64(check.MaxScore)
+		}
+	}
+
+	categories := make([]CategoryScore, 0, len(tallies))
+	var total, weights float64
+	for _, t := range tallies {
+		if t.possible > 0 {
+			ratio := t.earned / t.possible
+			t.Score = floorPercent(ratio)
+			if t.Weight > 0 {
+				total += t.Weight * ratio
+				weights += t.Weight
+			}
+		}
+		categories = append(categories, t.CategoryScore)
+	}
+	sort.Slice(categories, func(i, j int) bool { return categories[i].Name < categories[j].Name })
+
+	if weights == 0 {
+		return 0, categories
+	}
+	return floorPercent(total / weights), categories
+}
+
+func floorPercent(ratio float64) int {
+	// The epsilon keeps float noise from turning a perfect run into 99.
+	return int(math.Floor(ratio*100 + 1e-9))
+}
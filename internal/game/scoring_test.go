@@ -0,0 +1,121 @@
+package game
+
+import (
+	"fmt"
+	"testing"
+
+	api "github.com/danielvollbro/gohl-api"
+)
+
+func checks(prefix string, n int, passed bool) []api.CheckResult {
+	var result []api.CheckResult
+	for i := 0; i < n; i++ {
+		check := api.CheckResult{ID: fmt.Sprintf("%s%d", prefix, i), MaxScore: 1, Passed: passed}
+		if passed {
+			check.Score = 1
+		}
+		result = append(result, check)
+	}
+	return result
+}
+
+func TestScoring_CriticalChecksAreNotDrownedOut(t *testing.T) {
+	reports := []*api.ScanReport{
+		{PluginID: "system", Checks: checks("sys-", 100, true)},
+		{PluginID: "docker", Checks: checks("docker-", 5, false)},
+	}
+
+	plain, _ := Scoring{}.Score(reports)
+	if plain < 90 {
+		t.Fatalf("expected an unweighted score dominated by trivial checks, got %d", plain)
+	}
+
+	scoring := Scoring{
+		Checks: map[string]CheckRule{
+			"sys-*":    {Category: "hygiene", Severity: "low"},
+			"docker-*": {Category: "security", Severity: "critical"},
+		},
+	}
+	score, categories := scoring.Score(reports)
+	if score != 50 {
+		t.Errorf("expected both categories to count equally, got %d", score)
+	}
+
+	if len(categories) != 2 || categories[0].Name != "hygiene" || categories[1].Name != "security" {
+		t.Fatalf("unexpected categories: %+v", categories)
+	}
+	if categories[0].Score != 100 || categories[1].Score != 0 || categories[1].Failed != 5 || categories[1].Critical != 5 {
+		t.Errorf("unexpected subscores: %+v", categories)
+	}
+}
+
+func TestScoring_Weights(t *testing.T) {
+	reports := []*api.ScanReport{
+		{PluginID: "proxmox", Checks: []api.CheckResult{
+			{ID: "pve-backup", Passed: true, Score: 10, MaxScore: 10},
+			{ID: "pve-tls", Score: 0, MaxScore: 10},
+		}},
+		{PluginID: "system", Checks: []api.CheckResult{
+			{ID: "sys-disk", Score: 0, MaxScore: 10},
+		}},
+	}
+
+	scoring := Scoring{
+		Providers:  map[string]float64{"pve": 3},
+		Categories: map[string]float64{"security": 3},
+		Severities: map[string]float64{"high": 9},
+		Checks: map[string]CheckRule{
+			"pve-*":   {Category: "reliability"},
+			"pve-tls": {Category: "Security", Severity: "HIGH"},
+		},
+	}
+	scoring.AliasProvider("pve", "proxmox")
+
+	if rule := scoring.Rule("pve-tls"); rule.Category != "security" || rule.Severity != "high" {
+		t.Errorf("exact rule should win over pattern: %+v", rule)
+	}
+	if rule := scoring.Rule("sys-disk"); rule.Category != DefaultCategory || rule.Severity != DefaultSeverity {
+		t.Errorf("unexpected default rule: %+v", rule)
+	}
+
+	// general 0/100 and reliability 100/100 at weight 1, security 0/100 at
+	// weight 3.
+	score, categories := scoring.Score(reports)
+	if len(categories) != 3 {
+		t.Fatalf("unexpected categories: %+v", categories)
+	}
+	if categories[1].Name != "reliability" || categories[1].Score != 100 {
+		t.Errorf("unexpected reliability subscore: %+v", categories[1])
+	}
+	if score != 20 {
+		t.Errorf("expected weighted score 20, got %d", score)
+	}
+}
+
+func TestScoring_Validate(t *testing.T) {
+	invalid := []Scoring{
+		{Providers: map[string]float64{"system": -1}},
+		{Checks: map[string]CheckRule{"sys-*": {Severity: "blocker"}}},
+		{Checks: map[string]CheckRule{"[": {}}},
+	}
+	for _, scoring := range invalid {
+		if err := scoring.Validate(); err == nil {
+			t.Errorf("expected error for %+v", scoring)
+		}
+	}
+
+	valid := Scoring{
+		Severities: map[string]float64{"blocker": 20},
+		Checks:     map[string]CheckRule{"sys-*": {Severity: "blocker"}},
+	}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("custom severity rejected: %v", err)
+	}
+}
+
+func TestCompileReport_Unranked(t *testing.T) {
+	report := CompileReport([]*api.ScanReport{{PluginID: "empty"}}, "lab", Scoring{}, DefaultLadder())
+	if report.Score != 0 || report.RankID != "unranked" {
+		t.Errorf("expected an unranked report, got %d %s", report.Score, report.Rank)
+	}
+}
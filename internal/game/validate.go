@@ -0,0 +1,70 @@
+package game
+
+import (
+	"fmt"
+
+	api "github.com/danielvollbro/gohl-api"
+)
+
+// Warning describes something Validate had to fix or drop in a provider's
+// report before it was scored.
+type Warning struct {
+	Provider string `json:"provider"`
+	CheckID  string `json:"check_id,omitempty"`
+	Message  string `json:"message"`
+}
+
+func (w Warning) String() string {
+	if w.CheckID != "" {
+		return fmt.Sprintf("%s/%s: %s", w.Provider, w.CheckID, w.Message)
+	}
+	return fmt.Sprintf("%s: %s", w.Provider, w.Message)
+}
+
+// Validate makes a provider's report safe to score. Checks without an ID
+// and repeated IDs are dropped, scores are clamped to 0..MaxScore and a
+// missing PluginID is filled in with the provider name. Every change is
+// returned as a warning.
+func Validate(provider string, report *api.ScanReport) []Warning {
+	var warnings []Warning
+	warn := func(checkID, format string, args ...interface{}) {
+		warnings = append(warnings, Warning{Provider: provider, CheckID: checkID, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if report.PluginID == "" {
+		report.PluginID = provider
+		warn("", "report has no plugin_id, using provider name")
+	}
+
+	seen := make(map[string]bool, len(report.Checks))
+	checks := report.Checks[:0]
+	for i, check := range report.Checks {
+		switch {
+		case check.ID == "":
+			warn("", "check #%d has no id, dropped", i+1)
+			continue
+		case seen[check.ID]:
+			warn(check.ID, "duplicate check id, dropped")
+			continue
+		}
+		seen[check.ID] = true
+
+		if check.MaxScore < 0 {
+			warn(check.ID, "negative max_score %d, clamped to 0", check.MaxScore)
+			check.MaxScore = 0
+		}
+		if check.Score < 0 {
+			warn(check.ID, "negative score %d, clamped to 0", check.Score)
+			check.Score = 0
+		}
+		if check.Score > check.MaxScore {
+			warn(check.ID, "score %d exceeds max_score %d, clamped", check.Score, check.MaxScore)
+			check.Score = check.MaxScore
+		}
+
+		checks = append(checks, check)
+	}
+	report.Checks = checks
+
+	return warnings
+}
@@ -0,0 +1,64 @@
+package game
+
+import (
+	"testing"
+
+	api "github.com/danielvollbro/gohl-api"
+)
+
+func TestValidate(t *testing.T) {
+	report := &api.ScanReport{
+		Checks: []api.CheckResult{
+			{ID: "ok", Score: 3, MaxScore: 5},
+			{ID: "negative", Score: -4, MaxScore: 5},
+			{ID: "inflated", Score: 50, MaxScore: 5},
+			{ID: "ok", Score: 5, MaxScore: 5},
+			{ID: "", Score: 1, MaxScore: 1},
+			{ID: "bad-max", Score: 0, MaxScore: -1},
+		},
+	}
+
+	warnings := Validate("proxmox", report)
+
+	if report.PluginID != "proxmox" {
+		t.Errorf("empty plugin id not filled in: %q", report.PluginID)
+	}
+
+	want := map[string][2]int{
+		"ok":       {3, 5},
+		"negative": {0, 5},
+		"inflated": {5, 5},
+		"bad-max":  {0, 0},
+	}
+	if len(report.Checks) != len(want) {
+		t.Fatalf("expected %d checks to survive, got %+v", len(want), report.Checks)
+	}
+	for _, check := range report.Checks {
+		if got := [2]int{check.Score, check.MaxScore}; got != want[check.ID] {
+			t.Errorf("%s: score %v, want %v", check.ID, got, want[check.ID])
+		}
+	}
+
+	if len(warnings) != 6 {
+		t.Errorf("expected 6 warnings, got %d: %v", len(warnings), warnings)
+	}
+	for _, w := range warnings {
+		if w.Provider != "proxmox" || w.Message == "" {
+			t.Errorf("incomplete warning: %+v", w)
+		}
+	}
+}
+
+func TestValidate_CleanReport(t *testing.T) {
+	report := &api.ScanReport{
+		PluginID: "system",
+		Checks:   []api.CheckResult{{ID: "a", Passed: true, Score: 1, MaxScore: 1}, {ID: "b", MaxScore: 2}},
+	}
+
+	if warnings := Validate("system", report); len(warnings) != 0 {
+		t.Errorf("expected no warnings, got %v", warnings)
+	}
+	if len(report.Checks) != 2 {
+		t.Errorf("valid checks were dropped: %+v", report.Checks)
+	}
+}
@@ -0,0 +1,141 @@
+package game
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// FixXP is the base award for completing a quest, on top of its impact.
+	// It is only paid the first time a quest is completed, so a check that
+	// flips between failing and passing cannot be farmed for XP. Completing
+	// a regressed quest again refunds its regression penalties instead.
+	FixXP = 10
+
+	// RegressionXP is the base penalty for a regressed quest, on top of half
+	// its impact.
+	RegressionXP = 5
+
+	// StreakXP is awarded per day of the current streak, up to StreakDays,
+	// for the first scan of a day.
+	StreakXP = 5
+)
+
+// XPEntry is one line of the XP ledger.
+type XPEntry struct {
+	Time    time.Time `json:"time"`
+	Reason  string    `json:"reason"`
+	QuestID string    `json:"quest_id,omitempty"`
+	Amount  int       `json:"amount"`
+}
+
+// Ledger is the persisted XP history. Total never drops below zero; the
+// entries record the amounts that were actually applied.
+type Ledger struct {
+	Total   int       `json:"total"`
+	Entries []XPEntry `json:"entries,omitempty"`
+}
+
+// Progress is the XP state after a scan.
+type Progress struct {
+	XP          int       `json:"xp"`
+	Gained      int       `json:"gained"`
+	Level       int       `json:"level"`
+	LevelXP     int       `json:"level_xp"`
+	NextLevelXP int       `json:"next_level_xp"`
+	LeveledUp   bool      `json:"leveled_up,omitempty"`
+	Awards      []XPEntry `json:"awards,omitempty"`
+}
+
+// LevelThreshold is the total XP needed to reach a level. Each level takes
+// 100 XP more than the one before: 0, 100, 300, 600, 1000, ...
+func LevelThreshold(level int) int {
+	if level <= 1 {
+		return 0
+	}
+	return 50 * level * (level - 1)
+}
+
+// Level returns the level reached with xp, the XP earned inside it and the
+// XP the level spans.
+func Level(xp int) (level, into, span int) {
+	level = 1
+	for xp >= LevelThreshold(level+1) {
+		level++
+	}
+	return level, xp - LevelThreshold(level), LevelThreshold(level+1) - LevelThreshold(level)
+}
+
+// AwardXP books the XP for a scan: quests completed for the first time earn
+// XP, quests completed again get their regression penalties back, regressed
+// ones cost XP, and the first scan of a day during a streak earns a bonus. History holds the earlier reports, oldest first.
+func AwardXP(ledger Ledger, quests QuestUpdate, current Report, history []Report, now time.Time) (Ledger, Progress) {
+	before := ledger.Total
+	rewarded := make(map[string]bool)
+	outstanding := make(map[string]int)
+	for _, entry := range ledger.Entries {
+		switch entry.Reason {
+		case "fix":
+			rewarded[entry.QuestID] = true
+		case "regression", "refund":
+			outstanding[entry.QuestID] -= entry.Amount
+		}
+	}
+
+	var awards []XPEntry
+	book := func(reason, questID string, amount int) {
+		if ledger.Total+amount < 0 {
+			amount = -ledger.Total
+		}
+		if amount == 0 {
+			return
+		}
+		ledger.Total += amount
+		awards = append(awards, XPEntry{Time: now, Reason: reason, QuestID: questID, Amount: amount})
+	}
+
+	for _, quest := range quests.Completed {
+		if rewarded[quest.ID] {
+			book("refund", quest.ID, outstanding[quest.ID])
+			continue
+		}
+		rewarded[quest.ID] = true
+		book("fix", quest.ID, FixXP+int(math.Round(quest.Impact)))
+	}
+
+	if firstScanOfDay(history, now) {
+		reports := append(history[:len(history):len(history)], current)
+		if days := streak(reports, now); days >= 2 {
+			book("streak", "", StreakXP*min(days, StreakDays))
+		}
+	}
+
+	for _, quest := range quests.Regressed {
+		book("regression", quest.ID, -(RegressionXP + int(math.Round(quest.Impact/2))))
+	}
+
+	ledger.Entries = append(ledger.Entries, awards...)
+
+	level, into, span := Level(ledger.Total)
+	previousLevel, _, _ := Level(before)
+	return ledger, Progress{
+		XP:          ledger.Total,
+		Gained:      ledger.Total - before,
+		Level:       level,
+		LevelXP:     into,
+		NextLevelXP: span,
+		LeveledUp:   level > previousLevel,
+		Awards:      awards,
+	}
+}
+
+func firstScanOfDay(history []Report, now time.Time) bool {
+	if len(history) == 0 {
+		return true
+	}
+	last, err := time.Parse(time.RFC3339, history[len(history)-1].Timestamp)
+	if err != nil {
+		return true
+	}
+	return last.In(now.Location()).Format("2006-01-02") != now.Format("2006-01-02")
+}
@@ -0,0 +1,106 @@
+package game
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLevel(t *testing.T) {
+	cases := []struct {
+		xp, level, into, span int
+	}{
+		{0, 1, 0, 100},
+		{99, 1, 99, 100},
+		{100, 2, 0, 200},
+		{350, 3, 50, 300},
+		{1000, 5, 0, 500},
+	}
+
+	for _, c := range cases {
+		level, into, span := Level(c.xp)
+		if level != c.level || into != c.into || span != c.span {
+			t.Errorf("Level(%d) = %d, %d, %d; want %d, %d, %d", c.xp, level, into, span, c.level, c.into, c.span)
+		}
+	}
+}
+
+func TestAwardXP_FixesAndRegressions(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	history := []Report{scanAt(now.Add(-time.Hour), 50)}
+
+	update := QuestUpdate{Completed: []Quest{{ID: "system/sys-swap", Impact: 92}}}
+	ledger, progress := AwardXP(Ledger{}, update, scanAt(now, 60), history, now)
+	if ledger.Total != 102 || progress.Gained != 102 || progress.Level != 2 || !progress.LeveledUp {
+		t.Fatalf("unexpected progress after a fix: %+v", progress)
+	}
+	if len(ledger.Entries) != 1 || ledger.Entries[0].Reason != "fix" || ledger.Entries[0].QuestID != "system/sys-swap" {
+		t.Errorf("fix not recorded in the ledger: %+v", ledger.Entries)
+	}
+
+	update = QuestUpdate{Regressed: []Quest{{ID: "system/sys-swap", Impact: 10}}}
+	ledger, progress = AwardXP(ledger, update, scanAt(now, 50), history, now)
+	if ledger.Total != 92 || progress.Gained != -10 || progress.LeveledUp {
+		t.Errorf("unexpected progress after a regression: %+v", progress)
+	}
+
+	// XP never goes negative.
+	update = QuestUpdate{Regressed: []Quest{{ID: "system/sys-disk", Impact: 1000}}}
+	ledger, progress = AwardXP(ledger, update, scanAt(now, 10), history, now)
+	if ledger.Total != 0 || progress.Gained != -92 || ledger.Entries[len(ledger.Entries)-1].Amount != -92 {
+		t.Errorf("expected XP to bottom out at zero: %+v", progress)
+	}
+}
+
+func TestAwardXP_FixRegressFix(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	history := []Report{scanAt(now.Add(-time.Hour), 50)}
+	quest := Quest{ID: "system/sys-swap", Impact: 20}
+
+	ledger, _ := AwardXP(Ledger{}, QuestUpdate{Completed: []Quest{quest}}, scanAt(now, 60), history, now)
+	ledger, _ = AwardXP(ledger, QuestUpdate{Regressed: []Quest{quest}}, scanAt(now, 50), history, now)
+	if ledger.Total != 15 {
+		t.Fatalf("expected the regression to cost 15 XP, got %d", ledger.Total)
+	}
+
+	ledger, progress := AwardXP(ledger, QuestUpdate{Completed: []Quest{quest}}, scanAt(now, 60), history, now)
+	if ledger.Total != 30 || progress.Gained != 15 || progress.Awards[0].Reason != "refund" {
+		t.Errorf("fixing the check again should refund the penalty, got %d XP, %+v", ledger.Total, progress.Awards)
+	}
+}
+
+func TestAwardXP_FlipFloppingCheck(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	history := []Report{scanAt(now.Add(-time.Hour), 50)}
+	quest := Quest{ID: "system/sys-swap", Impact: 20}
+
+	// fail -> pass -> fail -> pass -> fail -> pass
+	ledger := Ledger{}
+	for i := 0; i < 3; i++ {
+		ledger, _ = AwardXP(ledger, QuestUpdate{Completed: []Quest{quest}}, scanAt(now, 60), history, now)
+		ledger, _ = AwardXP(ledger, QuestUpdate{Regressed: []Quest{quest}}, scanAt(now, 50), history, now)
+	}
+	ledger, _ = AwardXP(ledger, QuestUpdate{Completed: []Quest{quest}}, scanAt(now, 60), history, now)
+
+	if ledger.Total != 30 {
+		t.Errorf("flipping a check back and forth should neither earn nor drain XP, got %d", ledger.Total)
+	}
+}
+
+func TestAwardXP_StreakBonus(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	history := []Report{
+		scanAt(now.AddDate(0, 0, -2), 50),
+		scanAt(now.AddDate(0, 0, -1), 50),
+	}
+
+	ledger, progress := AwardXP(Ledger{}, QuestUpdate{}, scanAt(now, 50), history, now)
+	if ledger.Total != 3*StreakXP || len(progress.Awards) != 1 || progress.Awards[0].Reason != "streak" {
+		t.Fatalf("expected a three day streak bonus, got %+v", progress)
+	}
+
+	// Only the first scan of the day earns the bonus.
+	history = append(history, scanAt(now, 50))
+	if _, progress := AwardXP(ledger, QuestUpdate{}, scanAt(now.Add(time.Hour), 50), history, now.Add(time.Hour)); progress.Gained != 0 {
+		t.Errorf("second scan of the day earned %d XP", progress.Gained)
+	}
+}
@@ -0,0 +1,69 @@
+package binary
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+// DefaultMaxOutput bounds a provider's stdout unless its sandbox profile
+// sets a different limit. Stderr is only used in error messages, so it is
+// kept much smaller and going over never stops the provider.
+const (
+	DefaultMaxOutput = 16 << 20
+	maxStderr        = 64 << 10
+)
+
+var errOutputLimit = errors.New("output limit exceeded")
+
+// cappedWriter buffers up to limit bytes (0 means no limit). Once the limit
+// is hit the rest is discarded and onExceed, if set, is called to stop the
+// provider.
+type cappedWriter struct {
+	buf      bytes.Buffer
+	limit    uint64
+	exceeded bool
+	onExceed func()
+}
+
+func (w *cappedWriter) Write(p []byte) (int, error) {
+	if w.exceeded {
+		return len(p), nil
+	}
+	if w.limit > 0 && uint64(w.buf.Len()+len(p)) > w.limit {
+		w.exceeded = true
+		if w.onExceed != nil {
+			w.onExceed()
+		}
+		return len(p), nil
+	}
+	return w.buf.Write(p)
+}
+
+// cappedReader fails with errOutputLimit once more than limit bytes have
+// been read (0 means no limit).
+type cappedReader struct {
+	r     io.Reader
+	limit uint64
+	read  uint64
+}
+
+func (r *cappedReader) Read(p []byte) (int, error) {
+	if r.limit > 0 {
+		if r.read >= r.limit {
+			var probe [1]byte
+			n, err := r.r.Read(probe[:])
+			if n > 0 {
+				return 0, errOutputLimit
+			}
+			return 0, err
+		}
+		if remaining := r.limit - r.read; uint64(len(p)) > remaining {
+			p = p[:remaining]
+		}
+	}
+
+	n, err := r.r.Read(p)
+	r.read += uint64(n)
+	return n, err
+}
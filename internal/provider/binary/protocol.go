@@ -0,0 +1,212 @@
+package binary
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/danielvollbro/gohl/internal/compat"
+	"github.com/danielvollbro/gohl/internal/digest"
+
+	api "github.com/danielvollbro/gohl-api"
+)
+
+// ProtocolVersion is the newest stdin protocol this agent speaks. Providers
+// that are not configured for the handshake, or do not answer it, are run in
+// legacy mode, where config is only passed as GOHL_CONFIG_* environment
+// variables.
+const ProtocolVersion = 1
+
+const (
+	InfoFlag         = "--gohl-info"
+	handshakeTimeout = 5 * time.Second
+)
+
+// HandshakeCacheDir stores handshake answers keyed by the provider binary's
+// SHA-256, so each build is only asked once. Empty disables the disk cache.
+var HandshakeCacheDir = defaultHandshakeCacheDir()
+
+// Request is written as JSON to the provider's stdin in protocol mode.
+type Request struct {
+	ProtocolVersion int                    `json:"protocol_version"`
+	LabID           string                 `json:"lab_id"`
+	Config          map[string]interface{} `json:"config"`
+	Checks          []string               `json:"checks,omitempty"`
+	Stream          bool                   `json:"stream,omitempty"`
+}
+
+// Handshake is what a provider prints when invoked with --gohl-info.
+// Legacy providers produce an empty handshake.
+type Handshake struct {
+	Plugin       api.PluginInfo  `json:"plugin"`
+	Protocols    []int           `json:"protocols"`
+	ConfigSchema json.RawMessage `json:"config_schema,omitempty"`
+	Checks       []string        `json:"checks,omitempty"`
+	Streaming    bool            `json:"streaming,omitempty"`
+	Digest       string          `json:"digest"`
+
+	compat.Requirements
+}
+
+var (
+	handshakeMu    sync.Mutex
+	handshakeCache = make(map[string]Handshake)
+	digestCache    = make(map[string]cachedDigest)
+)
+
+// cachedDigest remembers a binary's digest until its size or mtime change,
+// so asking for the handshake does not hash the binary every time.
+type cachedDigest struct {
+	size    int64
+	modTime time.Time
+	digest  string
+}
+
+func defaultHandshakeCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "gohl", "handshakes")
+}
+
+// Handshake asks the provider who it is. Answers are cached in memory and on
+// disk per binary digest. Providers without UseHandshake get an empty
+// handshake and are never run with InfoFlag.
+func (p *BinaryProvider) Handshake(ctx context.Context) Handshake {
+	if !p.UseHandshake {
+		return Handshake{}
+	}
+
+	digest, err := binaryDigest(p.Path)
+	if err != nil {
+		return Handshake{}
+	}
+
+	handshakeMu.Lock()
+	cached, ok := handshakeCache[digest]
+	handshakeMu.Unlock()
+	if ok {
+		return cached
+	}
+
+	if hs, ok := loadHandshake(digest); ok {
+		storeHandshake(hs, false)
+		return hs
+	}
+
+	hs, complete := p.runHandshake(ctx)
+	hs.Digest = digest
+	if complete {
+		storeHandshake(hs, true)
+	}
+	return hs
+}
+
+// runHandshake reports complete=false when the provider could not be asked,
+// e.g. because the handshake timed out, so the result is not cached.
+func (p *BinaryProvider) runHandshake(ctx context.Context) (Handshake, bool) {
+	ctx, cancel := context.WithTimeout(ctx, handshakeTimeout)
+	defer cancel()
+
+	cmd, cleanup, err := p.Sandbox.Command(ctx, p.Path, InfoFlag)
+	if err != nil {
+		return Handshake{}, false
+	}
+	defer cleanup()
+	cmd.Env = append(cmd.Env, "GOHL_HANDSHAKE=1")
+
+	output, err := cmd.Output()
+	if ctx.Err() != nil {
+		return Handshake{}, false
+	}
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return Handshake{}, false
+	}
+	if err != nil {
+		return Handshake{}, true
+	}
+
+	var hs Handshake
+	if err := json.Unmarshal(bytes.TrimSpace(output), &hs); err != nil {
+		return Handshake{}, true
+	}
+	return hs, true
+}
+
+func loadHandshake(digest string) (Handshake, bool) {
+	if HandshakeCacheDir == "" {
+		return Handshake{}, false
+	}
+
+	data, err := os.ReadFile(filepath.Join(HandshakeCacheDir, digest+".json"))
+	if err != nil {
+		return Handshake{}, false
+	}
+
+	var hs Handshake
+	if err := json.Unmarshal(data, &hs); err != nil || hs.Digest != digest {
+		return Handshake{}, false
+	}
+	return hs, true
+}
+
+func storeHandshake(hs Handshake, persist bool) {
+	handshakeMu.Lock()
+	handshakeCache[hs.Digest] = hs
+	handshakeMu.Unlock()
+
+	if !persist || HandshakeCacheDir == "" {
+		return
+	}
+
+	if err := os.MkdirAll(HandshakeCacheDir, 0755); err != nil {
+		return
+	}
+	if data, err := json.MarshalIndent(hs, "", "  "); err == nil {
+		os.WriteFile(filepath.Join(HandshakeCacheDir, hs.Digest+".json"), data, 0644)
+	}
+}
+
+// Negotiate returns the highest protocol version both sides support, or 0
+// for legacy mode.
+func (hs Handshake) Negotiate() int {
+	best := 0
+	for _, version := range hs.Protocols {
+		if version <= ProtocolVersion && version > best {
+			best = version
+		}
+	}
+	return best
+}
+
+func binaryDigest(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+
+	handshakeMu.Lock()
+	cached, ok := digestCache[path]
+	handshakeMu.Unlock()
+	if ok && cached.size == info.Size() && cached.modTime.Equal(info.ModTime()) {
+		return cached.digest, nil
+	}
+
+	sum, err := digest.File(path)
+	if err != nil {
+		return "", err
+	}
+
+	handshakeMu.Lock()
+	digestCache[path] = cachedDigest{size: info.Size(), modTime: info.ModTime(), digest: sum}
+	handshakeMu.Unlock()
+	return sum, nil
+}
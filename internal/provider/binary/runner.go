@@ -4,24 +4,51 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
 	"os/exec"
+	"strconv"
 	"strings"
 
+	"github.com/danielvollbro/gohl/internal/sandbox"
+
 	api "github.com/danielvollbro/gohl-api"
 )
 
 type BinaryProvider struct {
 	Name string
 	Path string
+
+	// Used by the stdin protocol only; legacy providers receive the flattened
+	// config passed to Analyze instead.
+	LabID    string
+	Settings map[string]interface{}
+	Checks   []string
+
+	// Sandbox restricts how the binary is run. Nil runs it with the agent's
+	// environment and privileges.
+	Sandbox *sandbox.Profile
+
+	// UseHandshake asks the provider for its handshake before scanning. It
+	// is opt-in because a provider that ignores its arguments would run a
+	// whole scan to answer it; without it the legacy protocol is used.
+	UseHandshake bool
 }
 
 func New(name, path string) *BinaryProvider {
 	return &BinaryProvider{Name: name, Path: path}
 }
 
+// Info returns the identity the provider reports in its handshake, falling
+// back to a placeholder for legacy providers.
 func (p *BinaryProvider) Info() api.PluginInfo {
+	if info := p.Handshake(context.Background()).Plugin; info.ID != "" {
+		if info.Name == "" {
+			info.Name = info.ID
+		}
+		return info
+	}
+
 	return api.PluginInfo{
 		ID:   "external-" + p.Name,
 		Name: "External: " + p.Name,
@@ -29,21 +56,57 @@ func (p *BinaryProvider) Info() api.PluginInfo {
 }
 
 func (p *BinaryProvider) Analyze(ctx context.Context, config map[string]string) (*api.ScanReport, error) {
-	cmd := exec.CommandContext(ctx, p.Path)
+	cmd, cleanup, err := p.Sandbox.Command(ctx, p.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sandbox provider %s: %w", p.Path, err)
+	}
+	defer cleanup()
 
-	cmd.Env = os.Environ()
-	for k, v := range config {
-		envKey := fmt.Sprintf("GOHL_CONFIG_%s", strings.ToUpper(k))
-		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", envKey, v))
+	handshake := p.Handshake(ctx)
+	if err := handshake.Requirements.Check(); err != nil {
+		return nil, fmt.Errorf("provider %s is not compatible: %w", p.Path, err)
 	}
 
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
+	version := handshake.Negotiate()
+	stream := version > 0 && handshake.Streaming
+	if version > 0 {
+		request, err := json.Marshal(Request{
+			ProtocolVersion: version,
+			LabID:           p.LabID,
+			Config:          p.Settings,
+			Checks:          p.Checks,
+			Stream:          stream,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode request for provider %s: %w", p.Path, err)
+		}
 
-	output, err := cmd.Output()
+		cmd.Stdin = bytes.NewReader(request)
+		cmd.Env = append(cmd.Env, "GOHL_PROTOCOL="+strconv.Itoa(version))
+	} else {
+		for k, v := range config {
+			envKey := fmt.Sprintf("GOHL_CONFIG_%s", strings.ToUpper(k))
+			cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", envKey, v))
+		}
+	}
+
+	stderr := &cappedWriter{limit: maxStderr}
+	cmd.Stderr = stderr
+
+	if stream {
+		return p.analyzeStream(ctx, cmd, stderr, handshake)
+	}
+
+	stdout := &cappedWriter{limit: p.maxOutput(), onExceed: func() { cmd.Process.Kill() }}
+	cmd.Stdout = stdout
+
+	err = cmd.Run()
+	if stdout.exceeded {
+		return nil, fmt.Errorf("provider %s: %w", p.Path, errOutputLimit)
+	}
 
 	if err != nil {
-		errorMsg := strings.TrimSpace(stderr.String())
+		errorMsg := strings.TrimSpace(stderr.buf.String())
 
 		if errorMsg != "" {
 			return nil, fmt.Errorf("%s", errorMsg)
@@ -53,9 +116,58 @@ func (p *BinaryProvider) Analyze(ctx context.Context, config map[string]string)
 	}
 
 	var report api.ScanReport
-	if err := json.Unmarshal(output, &report); err != nil {
+	if err := json.Unmarshal(stdout.buf.Bytes(), &report); err != nil {
 		return nil, fmt.Errorf("invalid json from provider %s: %w", p.Path, err)
 	}
 
+	if report.PluginID == "" {
+		report.PluginID = handshake.Plugin.ID
+	}
+
 	return &report, nil
 }
+
+// analyzeStream runs a streaming provider. If the provider fails part way,
+// the checks it already sent are returned along with the error.
+func (p *BinaryProvider) analyzeStream(ctx context.Context, cmd *exec.Cmd, stderr *cappedWriter, handshake Handshake) (*api.ScanReport, error) {
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to execute provider %s: %w", p.Path, err)
+	}
+
+	report, done, readErr := readStream(ctx, &cappedReader{r: stdout, limit: p.maxOutput()})
+	if readErr != nil {
+		cmd.Process.Kill()
+	}
+	waitErr := cmd.Wait()
+
+	if report.PluginID == "" {
+		report.PluginID = handshake.Plugin.ID
+	}
+
+	switch {
+	case errors.Is(readErr, errOutputLimit):
+		return &report, fmt.Errorf("provider %s: %w", p.Path, readErr)
+	case readErr != nil:
+		return &report, fmt.Errorf("invalid stream from provider %s: %w", p.Path, readErr)
+	case waitErr != nil:
+		if errorMsg := strings.TrimSpace(stderr.buf.String()); errorMsg != "" {
+			return &report, fmt.Errorf("%s", errorMsg)
+		}
+		return &report, fmt.Errorf("failed to execute provider %s: %w", p.Path, waitErr)
+	case !done:
+		return &report, fmt.Errorf("provider %s exited before finishing its run", p.Path)
+	}
+
+	return &report, nil
+}
+
+func (p *BinaryProvider) maxOutput() uint64 {
+	if p.Sandbox != nil && p.Sandbox.MaxOutput > 0 {
+		return p.Sandbox.MaxOutput
+	}
+	return DefaultMaxOutput
+}
@@ -0,0 +1,395 @@
+package binary
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/danielvollbro/gohl/internal/sandbox"
+	"github.com/danielvollbro/gohl/pkg/plugin"
+)
+
+// Sandboxed providers are started through the test binary.
+func TestMain(m *testing.M) {
+	sandbox.Init()
+	os.Exit(m.Run())
+}
+
+func writeScript(t *testing.T, dir, name, body string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("shell script providers are not supported on windows")
+	}
+
+	original := HandshakeCacheDir
+	HandshakeCacheDir = filepath.Join(dir, "handshakes")
+	t.Cleanup(func() { HandshakeCacheDir = original })
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// handshaking returns a provider that is configured for the handshake.
+func handshaking(name, path string) *BinaryProvider {
+	provider := New(name, path)
+	provider.UseHandshake = true
+	return provider
+}
+
+func TestAnalyze_ProtocolMode(t *testing.T) {
+	dir, err := os.MkdirTemp("", "gohl-binary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	requestFile := filepath.Join(dir, "request.json")
+	script := writeScript(t, dir, "provider-modern", `
+if [ "$1" = "--gohl-info" ]; then
+  echo '{"protocols": [1, 99]}'
+  exit 0
+fi
+cat > "`+requestFile+`"
+echo "{\"plugin_id\": \"modern\", \"checks\": [], \"env\": \"$GOHL_CONFIG_URL|$GOHL_PROTOCOL\"}"
+`)
+
+	provider := handshaking("modern", script)
+	provider.LabID = "lab-42"
+	provider.Checks = []string{"modern-tls"}
+	provider.Settings = map[string]interface{}{
+		"url":   "https://pve.lab",
+		"nodes": []interface{}{"pve1", "pve2"},
+		"auth":  map[string]interface{}{"user": "root@pam"},
+	}
+
+	report, err := provider.Analyze(context.Background(), map[string]string{"url": "https://pve.lab"})
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if report.PluginID != "modern" {
+		t.Errorf("Wrong plugin id: %s", report.PluginID)
+	}
+
+	data, err := os.ReadFile(requestFile)
+	if err != nil {
+		t.Fatalf("Provider did not receive a request on stdin: %v", err)
+	}
+
+	var request Request
+	if err := json.Unmarshal(data, &request); err != nil {
+		t.Fatalf("Invalid request json: %v", err)
+	}
+
+	if request.ProtocolVersion != 1 || request.LabID != "lab-42" {
+		t.Errorf("Unexpected request header: %+v", request)
+	}
+	if len(request.Checks) != 1 || request.Checks[0] != "modern-tls" {
+		t.Errorf("Requested checks missing: %v", request.Checks)
+	}
+	if nodes, ok := request.Config["nodes"].([]interface{}); !ok || len(nodes) != 2 {
+		t.Errorf("Nested list was flattened: %#v", request.Config["nodes"])
+	}
+	if auth, ok := request.Config["auth"].(map[string]interface{}); !ok || auth["user"] != "root@pam" {
+		t.Errorf("Nested map was flattened: %#v", request.Config["auth"])
+	}
+}
+
+func TestAnalyze_LegacyMode(t *testing.T) {
+	dir, err := os.MkdirTemp("", "gohl-binary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	runs := filepath.Join(dir, "runs.log")
+	script := writeScript(t, dir, "provider-legacy", `
+echo "run $*" >> "`+runs+`"
+echo "{\"plugin_id\": \"legacy-$GOHL_CONFIG_URL-$GOHL_PROTOCOL\", \"checks\": []}"
+`)
+
+	provider := New("legacy", script)
+	report, err := provider.Analyze(context.Background(), map[string]string{"url": "pve"})
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+
+	if report.PluginID != "legacy-pve-" {
+		t.Errorf("Legacy provider did not get env config only, got plugin id %s", report.PluginID)
+	}
+
+	// Without opting in, the provider is never run just for a handshake.
+	if info := provider.Info(); info.ID != "external-legacy" {
+		t.Errorf("Expected placeholder info, got %+v", info)
+	}
+	if data, _ := os.ReadFile(runs); string(data) != "run \n" {
+		t.Errorf("Expected exactly one plain run, got %q", string(data))
+	}
+}
+
+func TestHandshake_Negotiate(t *testing.T) {
+	cases := []struct {
+		protocols []int
+		want      int
+	}{
+		{nil, 0},
+		{[]int{1}, 1},
+		{[]int{2, 3}, 0},
+		{[]int{0, 1, 5}, 1},
+	}
+
+	for _, c := range cases {
+		if got := (Handshake{Protocols: c.protocols}).Negotiate(); got != c.want {
+			t.Errorf("negotiate(%v) = %d, want %d", c.protocols, got, c.want)
+		}
+	}
+}
+
+func TestHandshake_InfoAndCache(t *testing.T) {
+	dir, err := os.MkdirTemp("", "gohl-binary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	counter := filepath.Join(dir, "handshakes.log")
+	script := writeScript(t, dir, "provider-proxmox", `
+if [ "$1" = "--gohl-info" ]; then
+  echo hit >> "`+counter+`"
+  echo '{"plugin": {"id": "proxmox", "name": "Proxmox VE", "version": "1.4.2"}, "protocols": [1], "checks": ["pve-backup", "pve-ha"], "config_schema": {"type": "object"}}'
+  exit 0
+fi
+echo '{"checks": []}'
+`)
+
+	provider := handshaking("proxmox", script)
+
+	info := provider.Info()
+	if info.ID != "proxmox" || info.Name != "Proxmox VE" || info.Version != "1.4.2" {
+		t.Errorf("Handshake info not used: %+v", info)
+	}
+
+	hs := provider.Handshake(context.Background())
+	if len(hs.Checks) != 2 || string(hs.ConfigSchema) != `{"type": "object"}` || hs.Digest == "" {
+		t.Errorf("Handshake incomplete: %+v", hs)
+	}
+
+	report, err := provider.Analyze(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.PluginID != "proxmox" {
+		t.Errorf("Empty plugin id should be filled from the handshake, got %q", report.PluginID)
+	}
+
+	// Forget the in-memory answer; the disk cache must still prevent a rerun.
+	handshakeMu.Lock()
+	delete(handshakeCache, hs.Digest)
+	handshakeMu.Unlock()
+
+	if handshaking("proxmox", script).Info().Version != "1.4.2" {
+		t.Error("Cached handshake not loaded from disk")
+	}
+
+	data, _ := os.ReadFile(counter)
+	if hits := strings.Count(string(data), "hit"); hits != 1 {
+		t.Errorf("Expected one handshake invocation, got %d", hits)
+	}
+
+	// Replacing the binary changes its digest, so the new build is asked.
+	writeScript(t, dir, "provider-proxmox", `
+echo '{"plugin": {"id": "proxmox", "version": "1.5.0"}, "protocols": [1]}'
+`)
+	if version := provider.Info().Version; version != "1.5.0" {
+		t.Errorf("Replaced binary reused the old handshake, got version %s", version)
+	}
+}
+
+func TestHandshake_LegacyFallback(t *testing.T) {
+	dir, err := os.MkdirTemp("", "gohl-binary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	script := writeScript(t, dir, "provider-old", "exit 1\n")
+
+	info := handshaking("old", script).Info()
+	if info.ID != "external-old" {
+		t.Errorf("Expected placeholder info for legacy provider, got %+v", info)
+	}
+}
+
+func TestAnalyze_Streaming(t *testing.T) {
+	dir, err := os.MkdirTemp("", "gohl-binary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	requestFile := filepath.Join(dir, "request.json")
+	script := writeScript(t, dir, "provider-stream", `
+if [ "$1" = "--gohl-info" ]; then
+  echo '{"plugin": {"id": "stream"}, "protocols": [1], "streaming": true}'
+  exit 0
+fi
+cat > "`+requestFile+`"
+echo '{"type": "progress", "message": "connecting", "current": 0, "total": 2}'
+echo '{"type": "check", "check": {"id": "a", "name": "A", "passed": true, "score": 5, "max_score": 5}, "total": 2}'
+echo ''
+echo '{"type": "check", "check": {"id": "b", "name": "B", "passed": false, "score": 0, "max_score": 5}, "total": 2}'
+echo '{"type": "done"}'
+`)
+
+	var events []plugin.Progress
+	ctx := plugin.WithProgress(context.Background(), func(p plugin.Progress) {
+		events = append(events, p)
+	})
+
+	report, err := handshaking("stream", script).Analyze(ctx, nil)
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+
+	if report.PluginID != "stream" || len(report.Checks) != 2 {
+		t.Fatalf("Unexpected report: %+v", report)
+	}
+	if report.Checks[1].ID != "b" || report.Checks[1].Passed {
+		t.Errorf("Checks not collected in order: %+v", report.Checks)
+	}
+
+	if len(events) != 3 || events[0].Message != "connecting" || events[2].Check == nil || events[2].Current != 2 {
+		t.Errorf("Unexpected progress events: %+v", events)
+	}
+
+	data, _ := os.ReadFile(requestFile)
+	var request Request
+	if err := json.Unmarshal(data, &request); err != nil || !request.Stream {
+		t.Errorf("Provider was not asked to stream: %s", data)
+	}
+}
+
+func TestAnalyze_StreamingKeepsPartialResults(t *testing.T) {
+	dir, err := os.MkdirTemp("", "gohl-binary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	script := writeScript(t, dir, "provider-crash", `
+if [ "$1" = "--gohl-info" ]; then
+  echo '{"plugin": {"id": "crashy"}, "protocols": [1], "streaming": true}'
+  exit 0
+fi
+echo '{"type": "check", "check": {"id": "a", "passed": true, "score": 1, "max_score": 1}}'
+echo "segfault while checking b" >&2
+exit 139
+`)
+
+	report, err := handshaking("crashy", script).Analyze(context.Background(), nil)
+	if err == nil || !strings.Contains(err.Error(), "segfault") {
+		t.Fatalf("Expected provider error, got %v", err)
+	}
+	if report == nil || len(report.Checks) != 1 || report.PluginID != "crashy" {
+		t.Fatalf("Partial results lost: %+v", report)
+	}
+}
+
+func TestAnalyze_StreamingWithoutDone(t *testing.T) {
+	dir, err := os.MkdirTemp("", "gohl-binary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	script := writeScript(t, dir, "provider-truncated", `
+if [ "$1" = "--gohl-info" ]; then
+  echo '{"protocols": [1], "streaming": true}'
+  exit 0
+fi
+echo '{"type": "check", "check": {"id": "a", "passed": true, "score": 1, "max_score": 1}}'
+echo '{"type": "check", "check": {"id": "b"'
+`)
+
+	report, err := handshaking("truncated", script).Analyze(context.Background(), nil)
+	if err == nil {
+		t.Fatal("Expected an error for a truncated stream")
+	}
+	if report == nil || len(report.Checks) != 1 {
+		t.Fatalf("Partial results lost: %+v", report)
+	}
+}
+
+func TestAnalyze_Sandboxed(t *testing.T) {
+	dir, err := os.MkdirTemp("", "gohl-binary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	t.Setenv("GITHUB_TOKEN", "secret")
+	script := writeScript(t, dir, "provider-env", `
+echo "{\"plugin_id\": \"env-$GITHUB_TOKEN-$GOHL_CONFIG_URL\", \"checks\": []}"
+`)
+
+	provider := New("env", script)
+	provider.Sandbox = &sandbox.Profile{}
+
+	report, err := provider.Analyze(context.Background(), map[string]string{"url": "pve"})
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if report.PluginID != "env--pve" {
+		t.Errorf("Expected a scrubbed environment with provider config, got %s", report.PluginID)
+	}
+}
+
+func TestAnalyze_OutputLimit(t *testing.T) {
+	dir, err := os.MkdirTemp("", "gohl-binary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	script := writeScript(t, dir, "provider-chatty", `
+if [ "$1" = "--gohl-info" ]; then
+  exit 1
+fi
+while true; do echo '{"plugin_id": "chatty", "checks": []}'; done
+`)
+
+	provider := handshaking("chatty", script)
+	provider.Sandbox = &sandbox.Profile{MaxOutput: 1024}
+
+	_, err = provider.Analyze(context.Background(), nil)
+	if err == nil || !strings.Contains(err.Error(), "output limit exceeded") {
+		t.Errorf("Expected output limit error, got %v", err)
+	}
+}
+
+func TestAnalyze_IncompatibleHandshake(t *testing.T) {
+	dir, err := os.MkdirTemp("", "gohl-binary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	script := writeScript(t, dir, "provider-future", `
+if [ "$1" = "--gohl-info" ]; then
+  echo '{"plugin": {"id": "future"}, "protocols": [1], "api_version": "2.0"}'
+  exit 0
+fi
+echo '{"checks": []}'
+`)
+
+	_, err = handshaking("future", script).Analyze(context.Background(), nil)
+	if err == nil || !strings.Contains(err.Error(), "not compatible") {
+		t.Errorf("Expected incompatible provider to be refused, got %v", err)
+	}
+}
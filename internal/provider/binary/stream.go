@@ -0,0 +1,78 @@
+package binary
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/danielvollbro/gohl/pkg/plugin"
+
+	api "github.com/danielvollbro/gohl-api"
+)
+
+// Message is one line of a streaming provider's stdout. Providers that set
+// "streaming" in their handshake print one message per line instead of a
+// single ScanReport, and end a complete run with a "done" message.
+type Message struct {
+	Type     string           `json:"type"`
+	Check    *api.CheckResult `json:"check,omitempty"`
+	Message  string           `json:"message,omitempty"`
+	Current  int              `json:"current,omitempty"`
+	Total    int              `json:"total,omitempty"`
+	PluginID string           `json:"plugin_id,omitempty"`
+}
+
+const (
+	MessageCheck    = "check"
+	MessageProgress = "progress"
+	MessageDone     = "done"
+)
+
+const maxMessageSize = 1 << 20
+
+// readStream collects the checks a provider streams to r, forwarding each of
+// them to the progress callback in ctx. done reports whether the provider
+// finished its run; without it the report only holds what arrived so far.
+func readStream(ctx context.Context, r io.Reader) (report api.ScanReport, done bool, err error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxMessageSize)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var msg Message
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			return report, false, fmt.Errorf("invalid message on line %d: %w", line, err)
+		}
+
+		switch msg.Type {
+		case MessageCheck:
+			if msg.Check == nil {
+				continue
+			}
+			report.Checks = append(report.Checks, *msg.Check)
+			plugin.ReportProgress(ctx, plugin.Progress{
+				Current: len(report.Checks),
+				Total:   msg.Total,
+				Check:   msg.Check,
+			})
+		case MessageProgress:
+			plugin.ReportProgress(ctx, plugin.Progress{
+				Message: msg.Message,
+				Current: msg.Current,
+				Total:   msg.Total,
+			})
+		case MessageDone:
+			report.PluginID = msg.PluginID
+			done = true
+		}
+	}
+
+	return report, done, scanner.Err()
+}
@@ -0,0 +1,143 @@
+package docker
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	api "github.com/danielvollbro/gohl-api"
+)
+
+type rule struct {
+	id          string
+	name        string
+	description string
+	maxScore    int
+	remediation string
+	violates    func(c *ContainerDetails) bool
+}
+
+var rules = []rule{
+	{
+		id:          "docker-restart-policy",
+		name:        "Restart Policies",
+		description: "Containers restart automatically after a crash or reboot",
+		maxScore:    10,
+		remediation: "Set a restart policy, e.g. 'restart: unless-stopped' in compose or '--restart unless-stopped'",
+		violates: func(c *ContainerDetails) bool {
+			policy := c.HostConfig.RestartPolicy.Name
+			return policy == "" || policy == "no"
+		},
+	},
+	{
+		id:          "docker-non-root",
+		name:        "Non-root Containers",
+		description: "Containers run as an unprivileged user",
+		maxScore:    15,
+		remediation: "Run the container as a non-root user, e.g. 'user: \"1000:1000\"' in compose",
+		violates: func(c *ContainerDetails) bool {
+			user := strings.SplitN(c.Config.User, ":", 2)[0]
+			return user == "" || user == "root" || user == "0"
+		},
+	},
+	{
+		id:          "docker-pinned-tags",
+		name:        "Pinned Image Tags",
+		description: "Images are pinned to a version instead of ':latest'",
+		maxScore:    10,
+		remediation: "Pin the image to an explicit version tag or digest",
+		violates: func(c *ContainerDetails) bool {
+			return usesLatestTag(c.Config.Image)
+		},
+	},
+	{
+		id:          "docker-healthcheck",
+		name:        "Healthchecks",
+		description: "Containers define a healthcheck",
+		maxScore:    10,
+		remediation: "Add a HEALTHCHECK to the image or a 'healthcheck:' block in compose",
+		violates: func(c *ContainerDetails) bool {
+			// An image's HEALTHCHECK can be switched off with ["NONE"].
+			check := c.Config.Healthcheck
+			return check == nil || len(check.Test) == 0 || check.Test[0] == "NONE"
+		},
+	},
+	{
+		id:          "docker-privileged",
+		name:        "No Privileged Containers",
+		description: "No container runs in privileged mode",
+		maxScore:    20,
+		remediation: "Remove 'privileged: true' and grant only the capabilities the container needs with 'cap_add'",
+		violates: func(c *ContainerDetails) bool {
+			return c.HostConfig.Privileged
+		},
+	},
+	{
+		id:          "docker-public-ports",
+		name:        "No Ports on All Interfaces",
+		description: "Published ports are bound to a specific interface instead of 0.0.0.0",
+		maxScore:    10,
+		remediation: "Bind published ports to a specific address, e.g. '127.0.0.1:8080:80', or put the service behind a reverse proxy",
+		violates: func(c *ContainerDetails) bool {
+			for _, bindings := range c.NetworkSettings.Ports {
+				for _, binding := range bindings {
+					if binding.HostIP == "" || binding.HostIP == "0.0.0.0" || binding.HostIP == "::" {
+						return true
+					}
+				}
+			}
+			return false
+		},
+	},
+}
+
+func usesLatestTag(image string) bool {
+	if strings.Contains(image, "@") {
+		return false
+	}
+
+	name := image
+	if slash := strings.LastIndex(image, "/"); slash >= 0 {
+		name = image[slash+1:]
+	}
+
+	colon := strings.LastIndex(name, ":")
+	return colon < 0 || name[colon+1:] == "latest"
+}
+
+// evaluate scores a rule by the share of compliant containers. Without any
+// containers there is nothing to earn, so the empty host is not a perfect one.
+func evaluate(r rule, containers []*ContainerDetails) api.CheckResult {
+	result := api.CheckResult{
+		ID:          r.id,
+		Name:        r.name,
+		Description: r.description,
+		MaxScore:    r.maxScore,
+	}
+
+	if len(containers) == 0 {
+		result.Passed = true
+		result.MaxScore = 0
+		result.Description = "No running containers to check"
+		return result
+	}
+
+	var offenders []string
+	for _, c := range containers {
+		if r.violates(c) {
+			offenders = append(offenders, strings.TrimPrefix(c.Name, "/"))
+		}
+	}
+
+	if len(offenders) == 0 {
+		result.Passed = true
+		result.Score = r.maxScore
+		return result
+	}
+
+	sort.Strings(offenders)
+	compliant := len(containers) - len(offenders)
+	result.Score = r.maxScore * compliant / len(containers)
+	result.Remediation = fmt.Sprintf("%s (%s)", r.remediation, strings.Join(offenders, ", "))
+	return result
+}
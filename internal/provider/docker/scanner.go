@@ -0,0 +1,88 @@
+package docker
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	api "github.com/danielvollbro/gohl-api"
+)
+
+const defaultSocket = "/var/run/docker.sock"
+
+type Scanner struct{}
+
+func New() *Scanner {
+	return &Scanner{}
+}
+
+func (s *Scanner) Info() api.PluginInfo {
+	return api.PluginInfo{
+		ID:          "docker",
+		Name:        "Docker",
+		Version:     "builtin",
+		Description: "Container hygiene checks via the Docker Engine API",
+		Author:      "gohl",
+	}
+}
+
+func (s *Scanner) Analyze(ctx context.Context, config map[string]string) (*api.ScanReport, error) {
+	client := NewClient(socketPath(config))
+
+	containers, err := client.ListContainers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ignored := parseIgnoreList(config["ignore"])
+
+	var details []*ContainerDetails
+	for _, container := range containers {
+		if isIgnored(container.Names, ignored) {
+			continue
+		}
+
+		detail, err := client.InspectContainer(ctx, container.ID)
+		if err != nil {
+			return nil, err
+		}
+		details = append(details, detail)
+	}
+
+	report := &api.ScanReport{PluginID: "docker"}
+	for _, rule := range rules {
+		report.Checks = append(report.Checks, evaluate(rule, details))
+	}
+
+	return report, nil
+}
+
+func socketPath(config map[string]string) string {
+	if socket := config["socket"]; socket != "" {
+		return strings.TrimPrefix(socket, "unix://")
+	}
+	if host := os.Getenv("DOCKER_HOST"); strings.HasPrefix(host, "unix://") {
+		return strings.TrimPrefix(host, "unix://")
+	}
+	return defaultSocket
+}
+
+func parseIgnoreList(raw string) map[string]bool {
+	ignored := make(map[string]bool)
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.TrimPrefix(strings.TrimSpace(name), "/")
+		if name != "" {
+			ignored[name] = true
+		}
+	}
+	return ignored
+}
+
+func isIgnored(names []string, ignored map[string]bool) bool {
+	for _, name := range names {
+		if ignored[strings.TrimPrefix(name, "/")] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,182 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	api "github.com/danielvollbro/gohl-api"
+)
+
+const containerList = `[
+	{"Id": "good", "Names": ["/web"], "Image": "nginx:1.27", "State": "running"},
+	{"Id": "bad", "Names": ["/legacy"], "Image": "legacy", "State": "running"},
+	{"Id": "skip", "Names": ["/ignored-db"], "Image": "mysql:latest", "State": "running"}
+]`
+
+const goodContainer = `{
+	"Id": "good",
+	"Name": "/web",
+	"Config": {"User": "1000:1000", "Image": "nginx:1.27", "Healthcheck": {"Test": ["CMD", "true"]}},
+	"HostConfig": {"Privileged": false, "RestartPolicy": {"Name": "unless-stopped"}},
+	"NetworkSettings": {"Ports": {"80/tcp": [{"HostIp": "127.0.0.1", "HostPort": "8080"}]}}
+}`
+
+const badContainer = `{
+	"Id": "bad",
+	"Name": "/legacy",
+	"Config": {"User": "", "Image": "legacy", "Healthcheck": {"Test": ["NONE"]}},
+	"HostConfig": {"Privileged": true, "RestartPolicy": {"Name": "no"}},
+	"NetworkSettings": {"Ports": {"22/tcp": [{"HostIp": "0.0.0.0", "HostPort": "2222"}]}}
+}`
+
+func startDockerMock(t *testing.T, handler http.Handler) string {
+	t.Helper()
+
+	dir, err := os.MkdirTemp("", "gohl-docker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	socket := filepath.Join(dir, "docker.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := httptest.NewUnstartedServer(handler)
+	ts.Listener = listener
+	ts.Start()
+	t.Cleanup(ts.Close)
+
+	return socket
+}
+
+func dockerMux(t *testing.T) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/containers/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(containerList))
+	})
+	mux.HandleFunc("/containers/good/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(goodContainer))
+	})
+	mux.HandleFunc("/containers/bad/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(badContainer))
+	})
+	mux.HandleFunc("/containers/skip/json", func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Ignored container should not be inspected")
+	})
+	return mux
+}
+
+func checkByID(report *api.ScanReport, id string) *api.CheckResult {
+	for i := range report.Checks {
+		if report.Checks[i].ID == id {
+			return &report.Checks[i]
+		}
+	}
+	return nil
+}
+
+func TestAnalyze_FlagsMisconfiguredContainers(t *testing.T) {
+	socket := startDockerMock(t, dockerMux(t))
+
+	report, err := New().Analyze(context.Background(), map[string]string{
+		"socket": "unix://" + socket,
+		"ignore": "ignored-db",
+	})
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+
+	if len(report.Checks) != len(rules) {
+		t.Fatalf("Expected %d checks, got %d", len(rules), len(report.Checks))
+	}
+
+	for _, check := range report.Checks {
+		if check.Passed {
+			t.Errorf("Expected %s to fail because of 'legacy'", check.ID)
+		}
+		if check.Score != check.MaxScore/2 {
+			t.Errorf("Expected half score for %s, got %d/%d", check.ID, check.Score, check.MaxScore)
+		}
+		if !strings.Contains(check.Remediation, "legacy") || strings.Contains(check.Remediation, "web") {
+			t.Errorf("Remediation for %s should only name 'legacy': %s", check.ID, check.Remediation)
+		}
+	}
+}
+
+func TestAnalyze_NoContainers(t *testing.T) {
+	socket := startDockerMock(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[]`))
+	}))
+
+	report, err := New().Analyze(context.Background(), map[string]string{"socket": socket})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, check := range report.Checks {
+		if !check.Passed || check.MaxScore != 0 || check.Score != 0 {
+			t.Errorf("Expected %s to pass without points when there are no containers, got %+v", check.ID, check)
+		}
+	}
+}
+
+func TestAnalyze_DaemonUnreachable(t *testing.T) {
+	_, err := New().Analyze(context.Background(), map[string]string{"socket": "/nonexistent/docker.sock"})
+	if err == nil {
+		t.Error("Expected error when the docker socket is missing")
+	}
+}
+
+func TestUsesLatestTag(t *testing.T) {
+	cases := map[string]bool{
+		"nginx":                         true,
+		"nginx:latest":                  true,
+		"nginx:1.27":                    false,
+		"registry.local:5000/app":       true,
+		"registry.local:5000/app:2.0":   false,
+		"ghcr.io/org/app@sha256:abc123": false,
+	}
+
+	for image, want := range cases {
+		if got := usesLatestTag(image); got != want {
+			t.Errorf("usesLatestTag(%q) = %v, want %v", image, got, want)
+		}
+	}
+}
+
+func TestHealthcheckRule(t *testing.T) {
+	var healthcheck rule
+	for _, r := range rules {
+		if r.id == "docker-healthcheck" {
+			healthcheck = r
+		}
+	}
+
+	cases := map[string]bool{
+		`{}`:                              true,
+		`{"Config": {"Healthcheck": {}}}`: true,
+		`{"Config": {"Healthcheck": {"Test": ["NONE"]}}}`:           true,
+		`{"Config": {"Healthcheck": {"Test": ["CMD", "true"]}}}`:    false,
+		`{"Config": {"Healthcheck": {"Test": ["CMD-SHELL", "x"]}}}`: false,
+	}
+
+	for raw, want := range cases {
+		var details ContainerDetails
+		if err := json.Unmarshal([]byte(raw), &details); err != nil {
+			t.Fatal(err)
+		}
+		if got := healthcheck.violates(&details); got != want {
+			t.Errorf("%s: violates = %v, want %v", raw, got, want)
+		}
+	}
+}
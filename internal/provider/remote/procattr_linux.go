@@ -0,0 +1,17 @@
+//go:build linux
+
+package remote
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcAttr makes the kernel stop the plugin if gohl dies without getting
+// the chance to shut it down.
+func setProcAttr(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Pdeathsig = syscall.SIGTERM
+}
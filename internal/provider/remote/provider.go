@@ -0,0 +1,311 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/rpc"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/danielvollbro/gohl/internal/sandbox"
+	"github.com/danielvollbro/gohl/pkg/plugin"
+
+	api "github.com/danielvollbro/gohl-api"
+)
+
+const (
+	DefaultPingInterval = 15 * time.Second
+	DefaultMaxRestarts  = 3
+	pingTimeout         = 5 * time.Second
+	shutdownGrace       = 2 * time.Second
+)
+
+// infoTimeout bounds the Info call; a plugin that does not answer in time is
+// replaced like one that outlived a scan deadline. It is only a variable so
+// tests can shorten it.
+var infoTimeout = plugin.InfoTimeout
+
+// Provider runs a provider binary as a long-lived plugin (started with
+// plugin.RPCFlag) and talks to it over JSON-RPC on stdio. Crashed or
+// unresponsive plugins are restarted on the next call, up to MaxRestarts.
+type Provider struct {
+	Name         string
+	Path         string
+	PingInterval time.Duration
+	MaxRestarts  int
+	Sandbox      *sandbox.Profile
+
+	mu       sync.Mutex
+	info     api.PluginInfo
+	proc     *process
+	restarts int
+	closed   bool
+}
+
+type process struct {
+	cmd    *exec.Cmd
+	client *plugin.RPCClient
+	stderr *tailBuffer
+	done   chan struct{}
+	stop   chan struct{}
+	err    error
+}
+
+func New(name, path string) *Provider {
+	return &Provider{
+		Name:         name,
+		Path:         path,
+		PingInterval: DefaultPingInterval,
+		MaxRestarts:  DefaultMaxRestarts,
+	}
+}
+
+func (p *Provider) Info() api.PluginInfo {
+	p.mu.Lock()
+	info := p.info
+	p.mu.Unlock()
+	if info.ID != "" {
+		return info
+	}
+
+	proc, err := p.ensure()
+	if err != nil {
+		return api.PluginInfo{ID: "external-" + p.Name, Name: "External: " + p.Name}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), infoTimeout)
+	defer cancel()
+
+	info, err = proc.client.InfoContext(ctx)
+	if ctx.Err() != nil {
+		p.kill(proc)
+	}
+	if err != nil || info.ID == "" {
+		return api.PluginInfo{ID: "external-" + p.Name, Name: "External: " + p.Name}
+	}
+
+	p.mu.Lock()
+	p.info = info
+	p.mu.Unlock()
+	return info
+}
+
+func (p *Provider) Analyze(ctx context.Context, config map[string]string) (*api.ScanReport, error) {
+	proc, err := p.ensure()
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := proc.client.Analyze(ctx, config)
+	if err == nil {
+		return report, nil
+	}
+
+	// The remote call cannot be cancelled, so a plugin that outlived its
+	// deadline is replaced before the next scan uses it.
+	if ctx.Err() != nil {
+		p.kill(proc)
+		return nil, ctx.Err()
+	}
+
+	var remoteErr rpc.ServerError
+	if errors.As(err, &remoteErr) {
+		return nil, err
+	}
+
+	if errors.Is(err, plugin.ErrResponseTooLarge) {
+		p.kill(proc)
+		return nil, fmt.Errorf("provider %s: %w", p.Name, err)
+	}
+
+	// Anything else means the connection broke; make sure the process is
+	// gone so the next call starts a new one.
+	p.kill(proc)
+	return nil, p.describe(proc, fmt.Errorf("provider %s crashed: %v", p.Name, proc.err))
+}
+
+// Close shuts the plugin down: stdin is closed so it can exit on its own,
+// and it is killed if it is still running after a grace period.
+func (p *Provider) Close() error {
+	p.mu.Lock()
+	p.closed = true
+	proc := p.proc
+	p.proc = nil
+	p.mu.Unlock()
+
+	if proc == nil {
+		return nil
+	}
+
+	close(proc.stop)
+	proc.client.Close()
+
+	select {
+	case <-proc.done:
+	case <-time.After(shutdownGrace):
+		proc.cmd.Process.Kill()
+		<-proc.done
+	}
+	return nil
+}
+
+func (p *Provider) ensure() (*process, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return nil, fmt.Errorf("provider %s is shut down", p.Name)
+	}
+
+	if p.proc != nil {
+		if !p.proc.exited() {
+			return p.proc, nil
+		}
+		if p.restarts >= p.MaxRestarts {
+			return nil, fmt.Errorf("provider %s crashed %d times, giving up", p.Name, p.restarts+1)
+		}
+		p.restarts++
+	}
+
+	proc, err := p.start()
+	if err != nil {
+		return nil, err
+	}
+	p.proc = proc
+	return proc, nil
+}
+
+func (p *Provider) start() (*process, error) {
+	cmd, cleanup, err := p.Sandbox.Command(context.Background(), p.Path, plugin.RPCFlag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sandbox provider %s: %w", p.Path, err)
+	}
+	cmd.Env = append(cmd.Env, "GOHL_PLUGIN=rpc")
+	setProcAttr(cmd)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+
+	proc := &process{
+		cmd:    cmd,
+		stderr: &tailBuffer{limit: 4096},
+		done:   make(chan struct{}),
+		stop:   make(chan struct{}),
+	}
+	cmd.Stderr = proc.stderr
+
+	if err := cmd.Start(); err != nil {
+		cleanup()
+		return nil, fmt.Errorf("failed to start provider %s: %w", p.Path, err)
+	}
+
+	proc.client = plugin.NewRPCClient(stdioConn{stdout, stdin})
+
+	go func() {
+		proc.err = cmd.Wait()
+		cleanup()
+		close(proc.done)
+	}()
+
+	go p.watch(proc)
+	return proc, nil
+}
+
+// watch pings the plugin periodically and kills it when it stops answering,
+// so the next call gets a fresh process.
+func (p *Provider) watch(proc *process) {
+	interval := p.PingInterval
+	if interval <= 0 {
+		interval = DefaultPingInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-proc.done:
+			return
+		case <-proc.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+			err := proc.client.Ping(ctx)
+			cancel()
+			if err != nil {
+				p.kill(proc)
+				return
+			}
+		}
+	}
+}
+
+func (p *Provider) kill(proc *process) {
+	if proc.cmd.Process != nil {
+		proc.cmd.Process.Kill()
+	}
+	<-proc.done
+}
+
+func (p *Provider) describe(proc *process, err error) error {
+	if msg := strings.TrimSpace(proc.stderr.String()); msg != "" {
+		return fmt.Errorf("%v: %s", err, msg)
+	}
+	return err
+}
+
+func (proc *process) exited() bool {
+	select {
+	case <-proc.done:
+		return true
+	default:
+		return false
+	}
+}
+
+type stdioConn struct {
+	io.ReadCloser
+	io.WriteCloser
+}
+
+func (c stdioConn) Close() error {
+	c.WriteCloser.Close()
+	return c.ReadCloser.Close()
+}
+
+// tailBuffer keeps the last limit bytes written to it, enough to explain
+// why a plugin died without buffering its whole stderr.
+type tailBuffer struct {
+	mu    sync.Mutex
+	limit int
+	data  []byte
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.data = append(b.data, p...)
+	if len(b.data) > b.limit {
+		b.data = b.data[len(b.data)-b.limit:]
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return string(b.data)
+}
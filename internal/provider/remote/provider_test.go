@@ -0,0 +1,220 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/danielvollbro/gohl/pkg/plugin"
+
+	api "github.com/danielvollbro/gohl-api"
+)
+
+// The test binary doubles as the plugin: with GOHL_TEST_PLUGIN set it serves
+// a fake scanner instead of running the tests.
+func TestMain(m *testing.M) {
+	if mode := os.Getenv("GOHL_TEST_PLUGIN"); mode != "" {
+		plugin.Serve(&testScanner{mode: mode})
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+type testScanner struct {
+	mode string
+}
+
+func (s *testScanner) Info() api.PluginInfo {
+	if s.mode == "slow-info" {
+		time.Sleep(time.Minute)
+	}
+	return api.PluginInfo{ID: "test-plugin", Name: "Test Plugin", Version: "0.1.0"}
+}
+
+func (s *testScanner) Analyze(ctx context.Context, config map[string]string) (*api.ScanReport, error) {
+	switch config["action"] {
+	case "crash":
+		os.Exit(3)
+	case "hang":
+		time.Sleep(time.Minute)
+	case "flood":
+		return &api.ScanReport{PluginID: strings.Repeat("x", plugin.MaxResponseSize+1)}, nil
+	}
+	return &api.ScanReport{
+		PluginID: "test-plugin",
+		Checks:   []api.CheckResult{{ID: "pid", Passed: true, Score: os.Getpid(), MaxScore: os.Getpid()}},
+	}, nil
+}
+
+func newTestProvider(t *testing.T) *Provider {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GOHL_TEST_PLUGIN", "serve")
+
+	p := New("test", exe)
+	t.Cleanup(func() { p.Close() })
+	return p
+}
+
+func pidOf(t *testing.T, report *api.ScanReport) int {
+	t.Helper()
+	if report == nil || len(report.Checks) != 1 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	return report.Checks[0].Score
+}
+
+func TestProviderReusesProcess(t *testing.T) {
+	p := newTestProvider(t)
+
+	if info := p.Info(); info.ID != "test-plugin" {
+		t.Fatalf("unexpected info: %+v", info)
+	}
+
+	first, err := p.Analyze(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	second, err := p.Analyze(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+
+	if pidOf(t, first) != pidOf(t, second) {
+		t.Error("expected both scans to be served by the same plugin process")
+	}
+}
+
+func TestProviderRestartsAfterCrash(t *testing.T) {
+	p := newTestProvider(t)
+
+	before, err := p.Analyze(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+
+	if _, err := p.Analyze(context.Background(), map[string]string{"action": "crash"}); err == nil {
+		t.Fatal("expected crashing scan to fail")
+	}
+
+	after, err := p.Analyze(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Analyze after crash failed: %v", err)
+	}
+	if pidOf(t, before) == pidOf(t, after) {
+		t.Error("expected a fresh plugin process after the crash")
+	}
+}
+
+func TestProviderGivesUpAfterMaxRestarts(t *testing.T) {
+	p := newTestProvider(t)
+	p.MaxRestarts = 1
+
+	crash := map[string]string{"action": "crash"}
+	for i := 0; i < 3; i++ {
+		p.Analyze(context.Background(), crash)
+	}
+
+	_, err := p.Analyze(context.Background(), nil)
+	if err == nil || !strings.Contains(err.Error(), "giving up") {
+		t.Errorf("expected provider to give up restarting, got %v", err)
+	}
+}
+
+func TestProviderTimeoutReplacesProcess(t *testing.T) {
+	p := newTestProvider(t)
+
+	before, err := p.Analyze(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := p.Analyze(ctx, map[string]string{"action": "hang"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+
+	after, err := p.Analyze(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Analyze after timeout failed: %v", err)
+	}
+	if pidOf(t, before) == pidOf(t, after) {
+		t.Error("expected hung plugin to be replaced")
+	}
+}
+
+func TestProviderRecoversFromKilledPlugin(t *testing.T) {
+	p := newTestProvider(t)
+
+	if _, err := p.Analyze(context.Background(), nil); err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+
+	proc := p.proc
+	proc.cmd.Process.Kill()
+
+	select {
+	case <-proc.done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("plugin process did not exit")
+	}
+
+	if _, err := p.Analyze(context.Background(), nil); err != nil {
+		t.Errorf("expected provider to recover, got %v", err)
+	}
+}
+
+func TestProviderClose(t *testing.T) {
+	p := newTestProvider(t)
+
+	if _, err := p.Analyze(context.Background(), nil); err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	proc := p.proc
+
+	p.Close()
+
+	if !proc.exited() {
+		t.Error("expected plugin process to exit on Close")
+	}
+	if _, err := p.Analyze(context.Background(), nil); err == nil {
+		t.Error("expected Analyze to fail after Close")
+	}
+}
+
+func TestProviderInfoTimeout(t *testing.T) {
+	original := infoTimeout
+	infoTimeout = 100 * time.Millisecond
+	t.Cleanup(func() { infoTimeout = original })
+
+	p := newTestProvider(t)
+	t.Setenv("GOHL_TEST_PLUGIN", "slow-info")
+
+	start := time.Now()
+	if info := p.Info(); info.ID != "external-test" {
+		t.Errorf("expected placeholder info, got %+v", info)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Info took %s despite the timeout", elapsed)
+	}
+}
+
+func TestProviderResponseTooLarge(t *testing.T) {
+	p := newTestProvider(t)
+
+	_, err := p.Analyze(context.Background(), map[string]string{"action": "flood"})
+	if !errors.Is(err, plugin.ErrResponseTooLarge) {
+		t.Fatalf("expected ErrResponseTooLarge, got %v", err)
+	}
+
+	// The flooding process is replaced on the next call.
+	if _, err := p.Analyze(context.Background(), nil); err != nil {
+		t.Errorf("Analyze after oversized response failed: %v", err)
+	}
+}
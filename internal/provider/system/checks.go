@@ -0,0 +1,244 @@
+package system
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	api "github.com/danielvollbro/gohl-api"
+)
+
+func pass(result api.CheckResult) api.CheckResult {
+	result.Passed = true
+	result.Score = result.MaxScore
+	result.Remediation = ""
+	return result
+}
+
+func fail(result api.CheckResult, reason error) api.CheckResult {
+	result.Passed = false
+	result.Score = 0
+	if reason != nil {
+		result.Error = reason.Error()
+	}
+	return result
+}
+
+func exists(root string, paths ...string) (string, bool) {
+	for _, p := range paths {
+		if _, err := os.Stat(filepath.Join(root, p)); err == nil {
+			return p, true
+		}
+	}
+	return "", false
+}
+
+func checkUptime(root string, maxDays int) api.CheckResult {
+	result := api.CheckResult{
+		ID:          "sys-uptime",
+		Name:        "Kernel Freshness",
+		Description: fmt.Sprintf("Host has been rebooted within the last %d days", maxDays),
+		MaxScore:    10,
+		Remediation: "Reboot the host to load the latest kernel and library updates.",
+	}
+
+	data, err := os.ReadFile(filepath.Join(root, "proc/uptime"))
+	if err != nil {
+		return fail(result, err)
+	}
+
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return fail(result, fmt.Errorf("empty /proc/uptime"))
+	}
+
+	seconds, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return fail(result, fmt.Errorf("invalid /proc/uptime: %w", err))
+	}
+
+	days := int(seconds / 86400)
+	result.Description = fmt.Sprintf("Host has been up for %d days (limit %d)", days, maxDays)
+	if days > maxDays {
+		return fail(result, nil)
+	}
+	return pass(result)
+}
+
+func checkPendingReboot(root string) api.CheckResult {
+	result := api.CheckResult{
+		ID:          "sys-reboot-required",
+		Name:        "No Pending Reboot",
+		Description: "No installed update is waiting for a reboot",
+		MaxScore:    15,
+		Remediation: "Installed packages require a restart. Schedule a reboot to activate them.",
+	}
+
+	if marker, found := exists(root, "var/run/reboot-required", "run/reboot-required"); found {
+		if pkgs, err := os.ReadFile(filepath.Join(root, marker+".pkgs")); err == nil {
+			names := strings.Fields(string(pkgs))
+			if len(names) > 0 {
+				result.Description = "Reboot required by: " + strings.Join(names, ", ")
+			}
+		}
+		return fail(result, nil)
+	}
+	return pass(result)
+}
+
+func checkSwap(root string) api.CheckResult {
+	result := api.CheckResult{
+		ID:          "sys-swap",
+		Name:        "Swap Configured",
+		Description: "The host has swap space to survive memory spikes",
+		MaxScore:    5,
+		Remediation: "Create a swap file (e.g. 'fallocate -l 2G /swapfile && mkswap /swapfile && swapon /swapfile') and add it to /etc/fstab.",
+	}
+
+	file, err := os.Open(filepath.Join(root, "proc/meminfo"))
+	if err != nil {
+		return fail(result, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "SwapTotal:" {
+			continue
+		}
+
+		kb, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return fail(result, fmt.Errorf("invalid SwapTotal: %w", err))
+		}
+		if kb == 0 {
+			return fail(result, nil)
+		}
+		return pass(result)
+	}
+
+	return fail(result, fmt.Errorf("SwapTotal not found in /proc/meminfo"))
+}
+
+func checkDiskUsage(root string, maxPercent int) api.CheckResult {
+	result := api.CheckResult{
+		ID:          "sys-disk-usage",
+		Name:        "Disk Space",
+		Description: fmt.Sprintf("Root filesystem usage is below %d%%", maxPercent),
+		MaxScore:    15,
+		Remediation: "Free up disk space: prune old logs ('journalctl --vacuum-size=500M'), package caches and unused container images.",
+	}
+
+	used, total, err := diskUsage(root)
+	if err != nil {
+		return fail(result, err)
+	}
+	if total == 0 {
+		return fail(result, fmt.Errorf("filesystem reports zero size"))
+	}
+
+	percent := int(used * 100 / total)
+	result.Description = fmt.Sprintf("Root filesystem is %d%% full (limit %d%%)", percent, maxPercent)
+	if percent >= maxPercent {
+		return fail(result, nil)
+	}
+	return pass(result)
+}
+
+func checkTimeSync(root string) api.CheckResult {
+	result := api.CheckResult{
+		ID:          "sys-time-sync",
+		Name:        "Clock Synchronized",
+		Description: "System clock is kept in sync via NTP",
+		MaxScore:    10,
+		Remediation: "Enable time synchronization: 'timedatectl set-ntp true' or install chrony.",
+	}
+
+	if _, found := exists(root, "run/systemd/timesync/synchronized"); found {
+		return pass(result)
+	}
+
+	if daemon, found := exists(root, "etc/chrony/chrony.conf", "etc/chrony.conf", "etc/ntp.conf", "etc/ntpsec/ntp.conf"); found {
+		result.Description = "NTP daemon configured (" + filepath.Base(daemon) + ")"
+		return pass(result)
+	}
+
+	return fail(result, nil)
+}
+
+func checkUnattendedUpgrades(root string) api.CheckResult {
+	result := api.CheckResult{
+		ID:          "sys-auto-updates",
+		Name:        "Automatic Security Updates",
+		Description: "Security updates are installed automatically",
+		MaxScore:    15,
+		Remediation: "Install and enable unattended-upgrades: 'apt install unattended-upgrades && dpkg-reconfigure -plow unattended-upgrades'.",
+	}
+
+	if data, err := os.ReadFile(filepath.Join(root, "etc/apt/apt.conf.d/20auto-upgrades")); err == nil {
+		if strings.Contains(string(data), `Unattended-Upgrade "1"`) {
+			return pass(result)
+		}
+		result.Description = "unattended-upgrades is installed but disabled"
+		return fail(result, nil)
+	}
+
+	if data, err := os.ReadFile(filepath.Join(root, "etc/dnf/automatic.conf")); err == nil {
+		if strings.Contains(strings.ReplaceAll(string(data), " ", ""), "apply_updates=yes") {
+			return pass(result)
+		}
+		result.Description = "dnf-automatic is installed but does not apply updates"
+		return fail(result, nil)
+	}
+
+	return fail(result, nil)
+}
+
+func checkTemperature(root string, maxCelsius int) api.CheckResult {
+	result := api.CheckResult{
+		ID:          "sys-temperature",
+		Name:        "Thermals",
+		Description: fmt.Sprintf("All thermal zones are below %d°C", maxCelsius),
+		MaxScore:    10,
+		Remediation: "Check fans, airflow and dust filters, and reduce sustained load on the host.",
+	}
+
+	zones, err := filepath.Glob(filepath.Join(root, "sys/class/thermal/thermal_zone*/temp"))
+	if err != nil {
+		return fail(result, err)
+	}
+	if len(zones) == 0 {
+		// Virtual machines and many containers expose no sensors.
+		result.Description = "No thermal zones exposed in /sys"
+		return pass(result)
+	}
+
+	hottest, hottestZone := 0, ""
+	for _, zone := range zones {
+		data, err := os.ReadFile(zone)
+		if err != nil {
+			continue
+		}
+		milli, err := strconv.Atoi(strings.TrimSpace(string(data)))
+		if err != nil {
+			continue
+		}
+		if hottestZone == "" || milli > hottest {
+			hottest, hottestZone = milli, filepath.Base(filepath.Dir(zone))
+		}
+	}
+	if hottestZone == "" {
+		return fail(result, fmt.Errorf("no readable thermal zone in /sys/class/thermal"))
+	}
+
+	celsius := hottest / 1000
+	result.Description = fmt.Sprintf("Hottest thermal zone %s is at %d°C (limit %d°C)", hottestZone, celsius, maxCelsius)
+	if celsius >= maxCelsius {
+		return fail(result, nil)
+	}
+	return pass(result)
+}
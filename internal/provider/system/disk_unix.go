@@ -0,0 +1,20 @@
+//go:build !windows
+
+package system
+
+import "syscall"
+
+// diskUsage reports used and total space the way df does: blocks reserved
+// for root count neither as used nor as available, so the total is what
+// unprivileged users can actually fill.
+var diskUsage = func(path string) (used, total uint64, err error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, 0, err
+	}
+
+	blockSize := uint64(stat.Bsize) // #nosec G115 -- block size is never negative
+	used = (stat.Blocks - stat.Bfree) * blockSize
+	total = used + stat.Bavail*blockSize
+	return used, total, nil
+}
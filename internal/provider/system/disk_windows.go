@@ -0,0 +1,9 @@
+//go:build windows
+
+package system
+
+import "errors"
+
+var diskUsage = func(path string) (used, total uint64, err error) {
+	return 0, 0, errors.New("disk usage check is not supported on windows")
+}
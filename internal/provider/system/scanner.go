@@ -0,0 +1,87 @@
+package system
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	api "github.com/danielvollbro/gohl-api"
+)
+
+const (
+	defaultRoot             = "/"
+	defaultMaxUptimeDays    = 60
+	defaultMaxDiskUsagePerc = 85
+	defaultMaxTemperature   = 80
+)
+
+type Scanner struct{}
+
+func New() *Scanner {
+	return &Scanner{}
+}
+
+func (s *Scanner) Info() api.PluginInfo {
+	return api.PluginInfo{
+		ID:          "system",
+		Name:        "System",
+		Version:     "builtin",
+		Description: "Host health checks based on /proc, /etc and /sys",
+		Author:      "gohl",
+	}
+}
+
+func (s *Scanner) Analyze(ctx context.Context, config map[string]string) (*api.ScanReport, error) {
+	root := config["root"]
+	if root == "" {
+		root = defaultRoot
+	}
+
+	maxUptimeDays, err := intOption(config, "max_uptime_days", defaultMaxUptimeDays)
+	if err != nil {
+		return nil, err
+	}
+
+	maxDiskUsage, err := intOption(config, "max_disk_usage", defaultMaxDiskUsagePerc)
+	if err != nil {
+		return nil, err
+	}
+
+	maxTemperature, err := intOption(config, "max_temperature", defaultMaxTemperature)
+	if err != nil {
+		return nil, err
+	}
+
+	checks := []func() api.CheckResult{
+		func() api.CheckResult { return checkUptime(root, maxUptimeDays) },
+		func() api.CheckResult { return checkPendingReboot(root) },
+		func() api.CheckResult { return checkSwap(root) },
+		func() api.CheckResult { return checkDiskUsage(root, maxDiskUsage) },
+		func() api.CheckResult { return checkTimeSync(root) },
+		func() api.CheckResult { return checkUnattendedUpgrades(root) },
+		func() api.CheckResult { return checkTemperature(root, maxTemperature) },
+	}
+
+	report := &api.ScanReport{PluginID: "system"}
+	for _, check := range checks {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		report.Checks = append(report.Checks, check())
+	}
+
+	return report, nil
+}
+
+func intOption(config map[string]string, key string, fallback int) (int, error) {
+	raw, ok := config[key]
+	if !ok || raw == "" {
+		return fallback, nil
+	}
+
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value for %s: %q", key, raw)
+	}
+	return value, nil
+}
@@ -0,0 +1,147 @@
+package system
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	api "github.com/danielvollbro/gohl-api"
+)
+
+func writeFixture(t *testing.T, root, path, content string) {
+	t.Helper()
+	full := filepath.Join(root, path)
+	if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func findCheck(t *testing.T, report *api.ScanReport, id string) api.CheckResult {
+	t.Helper()
+	for _, check := range report.Checks {
+		if check.ID == id {
+			return check
+		}
+	}
+	t.Fatalf("Check %s missing from report", id)
+	return api.CheckResult{}
+}
+
+func stubDiskUsage(t *testing.T, used, total uint64) {
+	original := diskUsage
+	diskUsage = func(string) (uint64, uint64, error) { return used, total, nil }
+	t.Cleanup(func() { diskUsage = original })
+}
+
+func TestAnalyze_HealthyHost(t *testing.T) {
+	root, err := os.MkdirTemp("", "gohl-system")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	writeFixture(t, root, "proc/uptime", "86400.50 172000.10\n")
+	writeFixture(t, root, "proc/meminfo", "MemTotal: 8000000 kB\nSwapTotal: 2097148 kB\n")
+	writeFixture(t, root, "run/systemd/timesync/synchronized", "")
+	writeFixture(t, root, "etc/apt/apt.conf.d/20auto-upgrades", "APT::Periodic::Update-Package-Lists \"1\";\nAPT::Periodic::Unattended-Upgrade \"1\";\n")
+	writeFixture(t, root, "sys/class/thermal/thermal_zone0/temp", "45000\n")
+	stubDiskUsage(t, 40, 100)
+
+	report, err := New().Analyze(context.Background(), map[string]string{"root": root})
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+
+	if report.PluginID != "system" {
+		t.Errorf("Wrong plugin id: %s", report.PluginID)
+	}
+
+	for _, check := range report.Checks {
+		if !check.Passed {
+			t.Errorf("Expected %s to pass, got error %q", check.ID, check.Error)
+		}
+		if check.Score != check.MaxScore {
+			t.Errorf("Expected full score for %s, got %d/%d", check.ID, check.Score, check.MaxScore)
+		}
+	}
+}
+
+func TestAnalyze_NeglectedHost(t *testing.T) {
+	root, err := os.MkdirTemp("", "gohl-system")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	writeFixture(t, root, "proc/uptime", "31536000.00 0.00\n")
+	writeFixture(t, root, "proc/meminfo", "MemTotal: 8000000 kB\nSwapTotal: 0 kB\n")
+	writeFixture(t, root, "var/run/reboot-required", "*** System restart required ***\n")
+	writeFixture(t, root, "var/run/reboot-required.pkgs", "linux-image-6.1.0\nlibc6\n")
+	writeFixture(t, root, "etc/apt/apt.conf.d/20auto-upgrades", "APT::Periodic::Unattended-Upgrade \"0\";\n")
+	writeFixture(t, root, "sys/class/thermal/thermal_zone0/temp", "41000\n")
+	writeFixture(t, root, "sys/class/thermal/thermal_zone1/temp", "92500\n")
+	stubDiskUsage(t, 95, 100)
+
+	report, err := New().Analyze(context.Background(), map[string]string{"root": root})
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+
+	for _, id := range []string{"sys-uptime", "sys-reboot-required", "sys-swap", "sys-disk-usage", "sys-time-sync", "sys-auto-updates", "sys-temperature"} {
+		check := findCheck(t, report, id)
+		if check.Passed {
+			t.Errorf("Expected %s to fail", id)
+		}
+		if check.Remediation == "" {
+			t.Errorf("Failed check %s has no remediation", id)
+		}
+	}
+
+	reboot := findCheck(t, report, "sys-reboot-required")
+	if reboot.Description != "Reboot required by: linux-image-6.1.0, libc6" {
+		t.Errorf("Wrong reboot description: %s", reboot.Description)
+	}
+
+	thermals := findCheck(t, report, "sys-temperature")
+	if thermals.Description != "Hottest thermal zone thermal_zone1 is at 92°C (limit 80°C)" {
+		t.Errorf("Wrong temperature description: %s", thermals.Description)
+	}
+}
+
+func TestAnalyze_ConfigurableThresholds(t *testing.T) {
+	root, err := os.MkdirTemp("", "gohl-system")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	writeFixture(t, root, "proc/uptime", "864000.00 0.00\n")
+	stubDiskUsage(t, 90, 100)
+
+	report, err := New().Analyze(context.Background(), map[string]string{
+		"root":            root,
+		"max_uptime_days": "5",
+		"max_disk_usage":  "95",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if findCheck(t, report, "sys-uptime").Passed {
+		t.Error("Uptime of 10 days should fail a 5 day limit")
+	}
+	if !findCheck(t, report, "sys-disk-usage").Passed {
+		t.Error("90% disk usage should pass a 95% limit")
+	}
+	if !findCheck(t, report, "sys-temperature").Passed {
+		t.Error("A host without thermal zones should pass the temperature check")
+	}
+
+	if _, err := New().Analyze(context.Background(), map[string]string{"max_uptime_days": "soon"}); err == nil {
+		t.Error("Expected error for invalid max_uptime_days")
+	}
+}
@@ -0,0 +1,168 @@
+package registry
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// maxBinarySize bounds how much data is extracted from an archive entry so a
+// crafted archive cannot fill the disk.
+const maxBinarySize = 512 << 20
+
+func archiveFormat(name string) string {
+	name = strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(name, ".tar.gz"):
+		return "tar.gz"
+	case strings.HasSuffix(name, ".tgz"):
+		return "tgz"
+	case strings.HasSuffix(name, ".zip"):
+		return "zip"
+	}
+	return ""
+}
+
+// binaryCandidates lists the file names the provider binary may have inside a
+// release archive. A configured name wins; otherwise the usual goreleaser
+// naming schemes are tried.
+func binaryCandidates(spec ProviderSpec) []string {
+	var names []string
+	if spec.Binary != "" {
+		names = []string{spec.Binary}
+	} else {
+		names = []string{"provider-" + spec.Name, spec.Name}
+		if parts := strings.Split(strings.TrimSuffix(spec.Source, "/"), "/"); len(parts) > 0 {
+			names = append(names, parts[len(parts)-1])
+		}
+	}
+
+	if runtime.GOOS == "windows" {
+		for _, name := range names {
+			if !strings.HasSuffix(name, ".exe") {
+				names = append(names, name+".exe")
+			}
+		}
+	}
+	return names
+}
+
+func matchesCandidate(entryName string, candidates []string) bool {
+	entryName = path.Clean(entryName)
+	for _, candidate := range candidates {
+		if strings.Contains(candidate, "/") {
+			if entryName == path.Clean(candidate) {
+				return true
+			}
+		} else if path.Base(entryName) == candidate {
+			return true
+		}
+	}
+	return false
+}
+
+func checkEntryPath(name string) error {
+	if !filepath.IsLocal(filepath.FromSlash(name)) {
+		return fmt.Errorf("unsafe path in archive: %s", name)
+	}
+	return nil
+}
+
+func extractBinary(archivePath, format string, candidates []string, dest string) error {
+	switch format {
+	case "tar.gz", "tgz":
+		return extractFromTarGz(archivePath, candidates, dest)
+	case "zip":
+		return extractFromZip(archivePath, candidates, dest)
+	}
+	return fmt.Errorf("unsupported archive format: %s", format)
+}
+
+func extractFromTarGz(archivePath string, candidates []string, dest string) error {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := checkEntryPath(header.Name); err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg || !matchesCandidate(header.Name, candidates) {
+			continue
+		}
+
+		return writeBinary(tr, dest)
+	}
+
+	return fmt.Errorf("binary %s not found in archive", strings.Join(candidates, " or "))
+}
+
+func extractFromZip(archivePath string, candidates []string, dest string) error {
+	zr, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	for _, file := range zr.File {
+		if err := checkEntryPath(file.Name); err != nil {
+			return err
+		}
+		if !file.Mode().IsRegular() || !matchesCandidate(file.Name, candidates) {
+			continue
+		}
+
+		rc, err := file.Open()
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+
+		return writeBinary(rc, dest)
+	}
+
+	return fmt.Errorf("binary %s not found in archive", strings.Join(candidates, " or "))
+}
+
+func writeBinary(r io.Reader, dest string) error {
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0755)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	written, err := io.Copy(out, io.LimitReader(r, maxBinarySize+1))
+	if err != nil {
+		return err
+	}
+	if written > maxBinarySize {
+		out.Close()
+		os.Remove(dest)
+		return fmt.Errorf("binary exceeds %d bytes", maxBinarySize)
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package registry
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func buildTarGz(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		header := &tar.Header{Name: name, Mode: 0755, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	gz.Close()
+	return buf.Bytes()
+}
+
+func buildZip(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	zw.Close()
+	return buf.Bytes()
+}
+
+func installArchive(t *testing.T, spec ProviderSpec, extension string, archive []byte) (string, error) {
+	t.Helper()
+
+	usePluginDir(t)
+
+	assetName := fmt.Sprintf("provider-test_1.0.0_%s_%s.%s", runtime.GOOS, runtime.GOARCH, extension)
+	sum := sha256.Sum256(archive)
+
+	var ts *httptest.Server
+	ts = useGitHubServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/download/archive":
+			w.Write(archive)
+		case "/download/checksums.txt":
+			fmt.Fprintf(w, "%s  %s\n", hex.EncodeToString(sum[:]), assetName)
+		default:
+			fmt.Fprintf(w, `{"tag_name": "v1.0.0", "assets": [
+				{"name": "%s", "browser_download_url": "%s/download/archive"},
+				{"name": "checksums.txt", "browser_download_url": "%s/download/checksums.txt"}
+			]}`, assetName, ts.URL, ts.URL)
+		}
+	}))
+
+	return EnsureProvider(spec)
+}
+
+func TestEnsureProvider_TarGzArchive(t *testing.T) {
+	archive := buildTarGz(t, map[string]string{
+		"README.md":     "docs",
+		"provider-test": "ARCHIVED BINARY",
+	})
+
+	path, err := installArchive(t, testSpec, "tar.gz", archive)
+	if err != nil {
+		t.Fatalf("EnsureProvider failed: %v", err)
+	}
+
+	content, _ := os.ReadFile(path)
+	if string(content) != "ARCHIVED BINARY" {
+		t.Errorf("Wrong binary extracted: %s", string(content))
+	}
+
+	if !cachedBinaryMatches(path, cacheWant{Version: "v1.0.0"}) {
+		t.Error("Extracted binary does not match its recorded digest")
+	}
+
+	if _, err := os.Stat(path + ".download"); !os.IsNotExist(err) {
+		t.Error("Downloaded archive was not cleaned up")
+	}
+}
+
+func TestEnsureProvider_ZipArchiveCustomBinary(t *testing.T) {
+	archive := buildZip(t, map[string]string{
+		"bin/gohl-scanner": "ZIPPED BINARY",
+	})
+
+	spec := testSpec
+	spec.Binary = "bin/gohl-scanner"
+
+	path, err := installArchive(t, spec, "zip", archive)
+	if err != nil {
+		t.Fatalf("EnsureProvider failed: %v", err)
+	}
+
+	content, _ := os.ReadFile(path)
+	if string(content) != "ZIPPED BINARY" {
+		t.Errorf("Wrong binary extracted: %s", string(content))
+	}
+}
+
+func TestEnsureProvider_ArchiveTraversal(t *testing.T) {
+	archive := buildTarGz(t, map[string]string{
+		"../../provider-test": "EVIL",
+	})
+
+	_, err := installArchive(t, testSpec, "tar.gz", archive)
+	if err == nil || !strings.Contains(err.Error(), "unsafe path") {
+		t.Fatalf("Expected traversal to be rejected, got %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(PluginDir, "..", "provider-test")); err == nil {
+		t.Error("Archive entry escaped the plugin dir")
+	}
+}
+
+func TestEnsureProvider_ArchiveMissingBinary(t *testing.T) {
+	archive := buildZip(t, map[string]string{"something-else": "x"})
+
+	if _, err := installArchive(t, testSpec, "zip", archive); err == nil {
+		t.Error("Expected error when the archive has no provider binary")
+	}
+}
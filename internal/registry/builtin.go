@@ -0,0 +1,12 @@
+package registry
+
+import (
+	"github.com/danielvollbro/gohl/internal/provider/docker"
+	"github.com/danielvollbro/gohl/internal/provider/system"
+	"github.com/danielvollbro/gohl/pkg/plugin"
+)
+
+func init() {
+	Register("system", func() plugin.Scanner { return system.New() })
+	Register("docker", func() plugin.Scanner { return docker.New() })
+}
@@ -1,6 +1,10 @@
 package registry
 
 import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -8,38 +12,116 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
+
+	"github.com/danielvollbro/gohl/internal/compat"
+	"github.com/danielvollbro/gohl/internal/digest"
 )
 
 var (
 	PluginDir      = "./plugins"
 	GitHubBaseURL  = "https://api.github.com"
 	ForceUserAgent = "gohl-agent-test" // Bra praxis
+
+	// Output receives download progress messages.
+	Output io.Writer = os.Stdout
 )
 
-type GitHubRelease struct {
-	TagName string `json:"tag_name"`
-	Assets  []struct {
-		Name               string `json:"name"`
-		BrowserDownloadURL string `json:"browser_download_url"`
-	} `json:"assets"`
+type releaseAsset struct {
+	Name         string
+	URL          string
+	Version      string
+	Archive      string
+	ChecksumsURL string
+	SignatureURL string
+	Local        bool
+
+	// Signer is the ID of the key that verified the release checksums.
+	Signer string
 }
 
-func EnsureProvider(name, repoSource, requestedVersion string) (string, error) {
-	if err := os.MkdirAll(PluginDir, 0755); err != nil {
-		return "", fmt.Errorf("failed to create plugin dir: %v", err)
+// ProviderSpec describes a downloadable provider as configured in gohl.yaml.
+// Update skips the lock file and resolves the requested version again.
+// Offline only accepts a verified binary that is already in PluginDir.
+// Binary names the executable inside release archives.
+type ProviderSpec struct {
+	Name             string
+	Source           string
+	Version          string
+	Binary           string
+	PublicKey        string
+	RequireSignature bool
+	Update           bool
+	Offline          bool
+}
+
+func EnsureProvider(spec ProviderSpec) (string, error) {
+	name := spec.Name
+	if err := checkName(name); err != nil {
+		return "", err
 	}
 
-	binaryName := "provider-" + name
-	if runtime.GOOS == "windows" {
-		binaryName += ".exe"
+	unlock, err := lockPluginDir()
+	if err != nil {
+		return "", err
 	}
+	defer unlock()
 
-	localPath := filepath.Join(PluginDir, binaryName)
+	localPath := binaryPath(name)
+	binaryName := filepath.Base(localPath)
 	versionPath := localPath + ".version"
-	downloadURL, resolvedVersion, err := resolveRemoteVersion(repoSource, requestedVersion)
+
+	lock, err := LoadLock()
 	if err != nil {
-		return "", fmt.Errorf("failed to resolve remote version: %v", err)
+		return "", fmt.Errorf("failed to read lock file: %v", err)
+	}
+
+	entry, locked := lock.lookup(spec)
+	if spec.Update {
+		locked = false
+	}
+
+	want := cacheWant{Version: spec.Version, Source: spec.Source, Signer: requiredSigner(spec)}
+	if locked {
+		want.Version = entry.Version
+		want.Digest = entry.binaryDigest()
+	}
+
+	// Pinned versions and offline runs are served from the cache without
+	// asking the releases API, as long as the binary still verifies. Ranges
+	// are resolved like "latest" so that newer matching releases are found.
+	if spec.Offline || (!spec.Update && !isLatest(want.Version) && !compat.IsRange(want.Version)) {
+		if path, ok := findCached(name, want); ok {
+			return path, nil
+		}
+		if spec.Offline {
+			if path, ok := findUnverified(name, want); ok {
+				return "", fmt.Errorf("provider '%s' (%s) is installed at %s without a recorded digest, so it cannot be verified offline; run 'gohl providers install %s' once while online", name, want.Version, path, name)
+			}
+			return "", fmt.Errorf("provider '%s' (%s) is not cached in %s and offline mode is enabled", name, want.Version, strings.Join(searchDirs(), ", "))
+		}
+	}
+
+	var asset *releaseAsset
+	expectedDigest := ""
+	if locked {
+		asset = &releaseAsset{Name: entry.Asset, URL: entry.AssetURL, Version: entry.Version, Archive: archiveFormat(entry.Asset), Signer: entry.Signer, Local: isLocalSource(entry.Source)}
+		expectedDigest = entry.Digest
+	} else {
+		asset, err = resolveRemoteVersion(spec.Source, spec.Version)
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve remote version: %v", err)
+		}
+	}
+
+	// A matching install elsewhere on the search path, e.g. the system-wide
+	// dir, is used as is.
+	if path, ok := findCached(name, cacheWant{Version: asset.Version, Source: want.Source, Signer: want.Signer, Digest: want.Digest}); ok && path != localPath {
+		if !locked {
+			return path, recordLock(lock, spec, asset, cachedAssetDigest(path, asset))
+		}
+		return path, nil
 	}
 
 	currentLocalVersion := ""
@@ -52,105 +134,569 @@ func EnsureProvider(name, repoSource, requestedVersion string) (string, error) {
 		binExists = true
 	}
 
-	if currentLocalVersion == resolvedVersion && binExists {
-		// TODO: Check checksum or version to force update if needed
-		return localPath, nil
+	if currentLocalVersion == asset.Version && binExists {
+		err := verifyCachedBinary(localPath, want, asset, spec)
+		if err == nil {
+			if !locked {
+				return localPath, recordLock(lock, spec, asset, cachedAssetDigest(localPath, asset))
+			}
+			return localPath, nil
+		}
+		fmt.Fprintf(Output, "⚠️  Cached provider '%s' failed verification (%v). Reinstalling...\n", name, err)
+	}
+
+	if expectedDigest == "" {
+		expectedDigest, err = fetchChecksum(asset, spec)
+		if err != nil {
+			return "", err
+		}
 	}
 
 	if currentLocalVersion == "" {
-		fmt.Printf("⬇️  Provider '%s' missing. Downloading from %s (%s)...\n", name, repoSource, resolvedVersion)
+		fmt.Fprintf(Output, "⬇️  Provider '%s' missing. Downloading from %s (%s)...\n", name, spec.Source, asset.Version)
 	} else {
-		fmt.Printf("⬆️  Updating %s: %s -> %s\n", name, currentLocalVersion, resolvedVersion)
+		fmt.Fprintf(Output, "⬆️  Updating %s: %s -> %s\n", name, currentLocalVersion, asset.Version)
 	}
 
-	if err := downloadFile(localPath, downloadURL); err != nil {
+	downloadPath, err := tempPath(binaryName + ".download")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(downloadPath)
+
+	actualDigest, err := downloadFile(downloadPath, asset.URL, asset.Local)
+	if err != nil {
 		return "", fmt.Errorf("download failed: %v", err)
 	}
 
-	if err := os.WriteFile(versionPath, []byte(resolvedVersion), 0644); err != nil {
-		fmt.Printf("⚠️ Warning: could not write version file: %v\n", err)
+	if actualDigest != expectedDigest {
+		return "", fmt.Errorf("checksum mismatch for %s: expected %s, got %s", asset.Name, expectedDigest, actualDigest)
 	}
 
-	if runtime.GOOS != "windows" {
-		if err := os.Chmod(localPath, 0755); err != nil {
-			return "", fmt.Errorf("failed to chmod: %v", err)
+	stagedPath := downloadPath
+	if asset.Archive != "" {
+		stagedPath, err = tempPath(binaryName + ".extract")
+		if err != nil {
+			return "", err
 		}
+		defer os.Remove(stagedPath)
+
+		if err := extractBinary(downloadPath, asset.Archive, binaryCandidates(spec), stagedPath); err != nil {
+			return "", fmt.Errorf("failed to extract %s: %v", asset.Name, err)
+		}
+	}
+
+	if err := installBinary(stagedPath, localPath, spec.Source, asset); err != nil {
+		return "", err
 	}
 
-	fmt.Printf("✅ Installed %s (%s) to %s\n", name, resolvedVersion, localPath)
+	fmt.Fprintf(Output, "✅ Installed %s (%s) to %s\n", name, asset.Version, localPath)
+
+	if !locked {
+		return localPath, recordLock(lock, spec, asset, actualDigest)
+	}
 	return localPath, nil
 }
 
-func resolveRemoteVersion(repoSource, version string) (string, string, error) {
-	parts := strings.Split(repoSource, "/")
-	if len(parts) < 3 {
-		return "", "", fmt.Errorf("invalid source format")
+// binaryPath is where a downloaded provider is installed in PluginDir.
+func binaryPath(name string) string {
+	return binaryPathIn(PluginDir, name)
+}
+
+func binaryPathIn(dir, name string) string {
+	binaryName := "provider-" + name
+	if runtime.GOOS == "windows" {
+		binaryName += ".exe"
 	}
-	owner, repo := parts[len(parts)-2], parts[len(parts)-1]
+	return filepath.Join(dir, binaryName)
+}
 
-	apiURL := fmt.Sprintf("%s/repos/%s/%s/releases/latest", GitHubBaseURL, owner, repo)
-	if version != "latest" && version != "" {
-		apiURL = fmt.Sprintf("%s/repos/%s/%s/releases/tags/%s", GitHubBaseURL, owner, repo, version)
+// checkName rejects provider names that would point outside the plugin dir
+// once they are part of a file name.
+func checkName(name string) error {
+	if name == "" || strings.Contains(name, "..") || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("invalid provider name '%s'", name)
 	}
+	return nil
+}
 
-	req, _ := http.NewRequest("GET", apiURL, nil)
+func tempPath(prefix string) (string, error) {
+	tmp, err := os.CreateTemp(PluginDir, "."+prefix+"-*")
+	if err != nil {
+		return "", err
+	}
+	return tmp.Name(), tmp.Close()
+}
 
-	token := os.Getenv("GITHUB_TOKEN")
-	if token != "" {
-		req.Header.Set("Authorization", "token "+token)
+// installBinary moves a verified binary into place. The version marker is
+// removed first and written last, so an interrupted install is never
+// mistaken for a complete one. The .source marker records where the binary
+// came from and the .signer marker the key the release was verified with,
+// if any.
+func installBinary(stagedPath, localPath, source string, asset *releaseAsset) error {
+	versionPath := localPath + ".version"
+	if err := os.Remove(versionPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to reset version file: %v", err)
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	if runtime.GOOS != "windows" {
+		if err := os.Chmod(stagedPath, 0755); err != nil {
+			return fmt.Errorf("failed to chmod: %v", err)
+		}
+	}
+
+	binaryDigest, err := digest.File(stagedPath)
 	if err != nil {
-		return "", "", err
+		return err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != 200 {
-		return "", "", fmt.Errorf("github api error: %s", resp.Status)
+	if err := os.Rename(stagedPath, localPath); err != nil {
+		return fmt.Errorf("failed to install binary: %v", err)
+	}
+
+	if err := writeFileAtomic(localPath+".sha256", []byte(binaryDigest), 0644); err != nil {
+		return fmt.Errorf("failed to write digest file: %v", err)
+	}
+
+	if err := writeFileAtomic(localPath+".source", []byte(source), 0644); err != nil {
+		return fmt.Errorf("failed to write source file: %v", err)
+	}
+
+	if err := writeSigner(localPath, asset.Signer); err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(versionPath, []byte(asset.Version), 0644); err != nil {
+		return fmt.Errorf("failed to write version file: %v", err)
+	}
+	return nil
+}
+
+func writeSigner(localPath, signer string) error {
+	signerPath := localPath + ".signer"
+	if signer == "" {
+		if err := os.Remove(signerPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to reset signer file: %v", err)
+		}
+		return nil
+	}
+	if err := writeFileAtomic(signerPath, []byte(signer), 0644); err != nil {
+		return fmt.Errorf("failed to write signer file: %v", err)
+	}
+	return nil
+}
+
+// cacheWant is what an installed binary has to match to be reused.
+type cacheWant struct {
+	// Version is a tag, a range or "latest".
+	Version string
+
+	// Source is the configured source. Installs that predate the .source
+	// marker are accepted for any source.
+	Source string
+
+	// Signer is the key the release must have been verified with, if any.
+	Signer string
+
+	// Digest is what the binary itself must hash to according to gohl.lock.
+	// It is empty for archives, whose locked digest is the one of the archive.
+	Digest string
+}
+
+// cachedBinaryMatches reports whether the installed binary has the wanted
+// version, or one inside the wanted range, and still matches the digest
+// recorded when it was installed as well as the one in want.
+func cachedBinaryMatches(localPath string, want cacheWant) bool {
+	if !cachedVersionMatches(localPath, want) {
+		return false
+	}
+
+	stored := readMarker(localPath + ".sha256")
+	if stored == "" || (want.Digest != "" && stored != want.Digest) {
+		return false
+	}
+
+	actual, err := digest.File(localPath)
+	return err == nil && actual == stored
+}
+
+// cachedVersionMatches reports whether the markers of an install match want,
+// without looking at the binary itself.
+func cachedVersionMatches(localPath string, want cacheWant) bool {
+	versionBytes, err := os.ReadFile(localPath + ".version")
+	if err != nil {
+		return false
+	}
+
+	cachedVersion := strings.TrimSpace(string(versionBytes))
+	switch {
+	case cachedVersion == "":
+		return false
+	case isLatest(want.Version):
+	case compat.IsRange(want.Version):
+		if !compat.Satisfies(cachedVersion, want.Version) {
+			return false
+		}
+	case cachedVersion != want.Version:
+		return false
+	}
+
+	if !sourceMatches(localPath, want.Source) {
+		return false
+	}
+
+	return want.Signer == "" || readMarker(localPath+".signer") == want.Signer
+}
+
+// sourceMatches reports whether an install came from source. Tags are only
+// unique within a source, so a binary from another one is never reused.
+func sourceMatches(localPath, source string) bool {
+	recorded := readMarker(localPath + ".source")
+	return recorded == "" || source == "" || recorded == source
+}
+
+// cachedAssetDigest is the release asset digest of a verified install, as far
+// as it is known without asking the source: for raw binaries it is the digest
+// of the binary itself.
+func cachedAssetDigest(localPath string, asset *releaseAsset) string {
+	if asset.Archive != "" {
+		return ""
+	}
+	return readMarker(localPath + ".sha256")
+}
+
+// recordLock stores the resolved asset in gohl.lock. The digest is the one of
+// the release asset as listed in its checksums file. When the caller does not
+// know it, which only happens for archives that were already installed, it is
+// taken from the previous lock entry for the same asset or fetched.
+func recordLock(lock *Lock, spec ProviderSpec, asset *releaseAsset, assetDigest string) error {
+	if LockFilePath == "" {
+		return nil
+	}
+
+	if assetDigest == "" {
+		previous, ok := lock.Providers[spec.Name]
+		if ok && previous.Source == spec.Source && previous.Version == asset.Version && previous.Asset == asset.Name {
+			assetDigest = previous.Digest
+		}
+	}
+
+	if assetDigest == "" {
+		digest, err := fetchChecksum(asset, spec)
+		if err != nil {
+			return err
+		}
+		assetDigest = digest
+	}
+
+	lock.Providers[spec.Name] = LockEntry{
+		Name:      spec.Name,
+		Source:    spec.Source,
+		Requested: spec.Version,
+		Version:   asset.Version,
+		Asset:     asset.Name,
+		AssetURL:  asset.URL,
+		Digest:    assetDigest,
+		Signer:    asset.Signer,
+	}
+
+	if err := lock.Save(); err != nil {
+		return fmt.Errorf("failed to write lock file: %v", err)
+	}
+	return nil
+}
+
+// verifyCachedBinary compares the installed binary with the digest recorded at
+// install time and, for locked raw binaries, with the one in gohl.lock. Raw
+// binaries installed before digest files existed are checked against the
+// release checksums instead and get a digest file on success. Providers that
+// require a signature only reuse binaries whose release was verified with
+// their key.
+func verifyCachedBinary(localPath string, want cacheWant, asset *releaseAsset, spec ProviderSpec) error {
+	digestPath := localPath + ".sha256"
+	actual, err := digest.File(localPath)
+	if err != nil {
+		return err
+	}
+
+	if want.Digest != "" && want.Digest != actual {
+		return fmt.Errorf("digest does not match gohl.lock")
+	}
+
+	if !sourceMatches(localPath, want.Source) {
+		return fmt.Errorf("installed from %s", readMarker(localPath+".source"))
+	}
+
+	if stored, err := os.ReadFile(digestPath); err == nil {
+		if strings.TrimSpace(string(stored)) != actual {
+			return fmt.Errorf("digest mismatch")
+		}
+		if want.Signer == "" {
+			return nil
+		}
+		if readMarker(localPath+".signer") != want.Signer {
+			return fmt.Errorf("release was not verified with the configured public_key")
+		}
+		asset.Signer = want.Signer
+		return nil
+	}
+
+	if asset.Archive != "" {
+		return fmt.Errorf("no recorded digest")
+	}
+
+	expected, err := fetchChecksum(asset, spec)
+	if err != nil {
+		return err
+	}
+	if expected != actual {
+		return fmt.Errorf("digest mismatch")
+	}
+
+	if err := writeSigner(localPath, asset.Signer); err != nil {
+		return err
+	}
+	return writeFileAtomic(digestPath, []byte(actual), 0644)
+}
+
+// manifestName is the release asset declaring what a provider release needs
+// from the agent, see compat.Requirements.
+const manifestName = "gohl-provider.json"
+
+// resolveRemoteVersion finds the release to install for a requested version.
+// Ranges pick the newest compatible release; if "latest" is too new for this
+// agent, the newest older release that still works is used instead.
+func resolveRemoteVersion(repoSource, version string) (*releaseAsset, error) {
+	source, err := NewSource(repoSource)
+	if err != nil {
+		return nil, err
+	}
+
+	if compat.IsRange(version) {
+		return resolveRange(source, version)
 	}
 
-	var release GitHubRelease
-	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
-		return "", "", err
+	release, err := source.Release(version)
+	if err != nil {
+		return nil, err
+	}
+
+	asset, err := selectAsset(release)
+	if err != nil {
+		return nil, err
 	}
 
-	expectedSuffix := fmt.Sprintf("_%s_%s", runtime.GOOS, runtime.GOARCH)
+	if err := checkRequirements(release); err != nil {
+		if !isLatest(version) {
+			return nil, fmt.Errorf("release %s: %v", release.Version, err)
+		}
+		fmt.Fprintf(Output, "⚠️  Latest release %s %v, looking for an older one...\n", release.Version, err)
+		return resolveRange(source, "*")
+	}
+	return asset, nil
+}
+
+func resolveRange(source Source, constraint string) (*releaseAsset, error) {
+	wanted, err := compat.ParseConstraint(constraint)
+	if err != nil {
+		return nil, err
+	}
+
+	releases, err := source.Releases()
+	if err != nil {
+		return nil, err
+	}
+
+	type candidate struct {
+		release *Release
+		version compat.Version
+	}
+	var candidates []candidate
+	for i := range releases {
+		parsed, err := compat.ParseVersion(releases[i].Version)
+		if err != nil || !wanted.Check(parsed) {
+			continue
+		}
+		candidates = append(candidates, candidate{&releases[i], parsed})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].version.Compare(candidates[j].version) > 0
+	})
+
+	var skipped []string
+	for _, c := range candidates {
+		asset, err := selectAsset(c.release)
+		if err == nil {
+			err = checkRequirements(c.release)
+		}
+		if err != nil {
+			skipped = append(skipped, fmt.Sprintf("%s: %v", c.release.Version, err))
+			continue
+		}
+		return asset, nil
+	}
+
+	if len(skipped) == 0 {
+		return nil, fmt.Errorf("no release matches %s", constraint)
+	}
+	return nil, fmt.Errorf("no release matching %s works with this agent (%s)", constraint, strings.Join(skipped, "; "))
+}
+
+// checkRequirements reads the release requirements, from the source or the
+// manifest asset, and checks them against this agent. Releases that declare
+// nothing are accepted.
+func checkRequirements(release *Release) error {
+	requires := release.Requires
+	if requires.IsZero() {
+		for _, asset := range release.Assets {
+			if !strings.EqualFold(asset.Name, manifestName) {
+				continue
+			}
+			data, err := fetchBytes(asset.URL, release.Local)
+			if err != nil {
+				return fmt.Errorf("failed to download %s: %v", manifestName, err)
+			}
+			if err := json.Unmarshal(data, &requires); err != nil {
+				return fmt.Errorf("invalid %s: %v", manifestName, err)
+			}
+			break
+		}
+	}
+	return requires.Check()
+}
+
+func selectAsset(release *Release) (*releaseAsset, error) {
+	platform := strings.ToLower(fmt.Sprintf("_%s_%s", runtime.GOOS, runtime.GOARCH))
+	expectedSuffix := platform
 	if runtime.GOOS == "windows" {
 		expectedSuffix += ".exe"
 	}
-	expectedSuffix = strings.ToLower(expectedSuffix)
 
+	// Raw binaries are preferred over archives when a release ships both.
+	result := &releaseAsset{Version: release.Version, Local: release.Local}
+	var archive *releaseAsset
 	for _, asset := range release.Assets {
 		name := strings.ToLower(asset.Name)
-		if strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".zip") || strings.HasSuffix(name, ".txt") {
+		if strings.HasSuffix(name, "checksums.txt") {
+			result.ChecksumsURL = asset.URL
 			continue
 		}
-		if strings.HasSuffix(name, expectedSuffix) {
-			return asset.BrowserDownloadURL, release.TagName, nil
+		if strings.HasSuffix(name, "checksums.txt.sig") {
+			result.SignatureURL = asset.URL
+			continue
+		}
+		if format := archiveFormat(name); format != "" {
+			if archive == nil && strings.HasSuffix(strings.TrimSuffix(name, "."+format), platform) {
+				archive = &releaseAsset{Name: asset.Name, URL: asset.URL, Archive: format}
+			}
+			continue
 		}
+		if strings.HasSuffix(name, ".txt") {
+			continue
+		}
+		if result.URL == "" && strings.HasSuffix(name, expectedSuffix) {
+			result.Name = asset.Name
+			result.URL = asset.URL
+		}
+	}
+
+	if result.URL == "" && archive != nil {
+		result.Name, result.URL, result.Archive = archive.Name, archive.URL, archive.Archive
+	}
+
+	if result.URL == "" {
+		return nil, fmt.Errorf("no binary or archive ending in '%s' found in release %s", expectedSuffix, release.Version)
 	}
 
-	return "", "", fmt.Errorf("no binary ending in '%s' found in release %s", expectedSuffix, release.TagName)
+	return result, nil
 }
 
-func downloadFile(filepath string, url string) error {
-	resp, err := http.Get(url)
+func fetchChecksum(asset *releaseAsset, spec ProviderSpec) (string, error) {
+	if asset.ChecksumsURL == "" {
+		return "", fmt.Errorf("release %s has no checksums file, refusing to install unverified binary", asset.Version)
+	}
+
+	checksums, err := fetchBytes(asset.ChecksumsURL, asset.Local)
 	if err != nil {
-		return err
+		return "", fmt.Errorf("failed to download checksums: %v", err)
+	}
+
+	if err := verifySignature(checksums, asset, spec); err != nil {
+		return "", err
+	}
+
+	return parseChecksums(bytes.NewReader(checksums), asset.Name)
+}
+
+func fetchBytes(url string, local bool) ([]byte, error) {
+	body, err := openURL(url, local)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	return io.ReadAll(body)
+}
+
+// openURL opens a release asset, which is either served over HTTP or lives
+// in a local mirror addressed with a file:// URL. Only local sources may hand
+// out file:// URLs, so a remote API response cannot point at files on this
+// machine.
+func openURL(rawURL string, local bool) (io.ReadCloser, error) {
+	if path, ok := strings.CutPrefix(rawURL, "file://"); ok {
+		if !local {
+			return nil, fmt.Errorf("refusing to open %s from a remote source", rawURL)
+		}
+		return os.Open(filepath.FromSlash(path))
+	}
+
+	resp, err := http.Get(rawURL)
+	if err != nil {
+		return nil, err
 	}
-	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
-		return fmt.Errorf("status %s", resp.Status)
+		resp.Body.Close()
+		return nil, fmt.Errorf("status %s", resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// parseChecksums reads a goreleaser/sha256sum style file ("<hex>  <name>").
+func parseChecksums(r io.Reader, assetName string) (string, error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		if strings.TrimPrefix(fields[1], "*") == assetName {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
 	}
 
-	out, err := os.Create(filepath)
+	return "", fmt.Errorf("no checksum for %s in checksums file", assetName)
+}
+
+func downloadFile(filepath string, url string, local bool) (string, error) {
+	body, err := openURL(url, local)
 	if err != nil {
-		return err
+		return "", err
+	}
+	defer body.Close()
+
+	out, err := os.OpenFile(filepath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return "", err
 	}
 	defer out.Close()
 
-	_, err = io.Copy(out, resp.Body)
-	return err
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(out, hash), body); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
 }
@@ -1,20 +1,37 @@
 package registry
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
+	"sync"
 	"testing"
 )
 
-func mockGitHubResponse(downloadURL, tagName string) string {
+func testAssetName() string {
 	assetName := fmt.Sprintf("provider-test_%s_%s", runtime.GOOS, runtime.GOARCH)
 	if runtime.GOOS == "windows" {
 		assetName += ".exe"
 	}
+	return assetName
+}
+
+func checksumsFor(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return fmt.Sprintf("%s  %s\n", hex.EncodeToString(sum[:]), testAssetName())
+}
+
+var testSpec = ProviderSpec{Name: "test", Source: "github.com/fake/repo", Version: "latest"}
+
+func mockGitHubResponse(downloadURL, tagName string) string {
+	assetName := testAssetName()
+	checksumsURL := strings.TrimSuffix(downloadURL, "/binary") + "/checksums.txt"
 
 	return fmt.Sprintf(`{
 		"tag_name": "%s",
@@ -25,40 +42,44 @@ func mockGitHubResponse(downloadURL, tagName string) string {
 			},
 			{
 				"name": "provider-test_checksums.txt",
-				"browser_download_url": "http://ignore.me"
+				"browser_download_url": "%s"
 			}
 		]
-	}`, tagName, assetName, downloadURL)
+	}`, tagName, assetName, downloadURL, checksumsURL)
 }
 
-func TestEnsureProvider_NewInstall(t *testing.T) {
-	tempDir, err := os.MkdirTemp("", "gohl-plugins")
-	if err != nil {
-		t.Fatal(err)
-	}
-	defer os.RemoveAll(tempDir)
+// useGitHubServer serves handler as the GitHub API for the rest of the test.
+func useGitHubServer(t *testing.T, handler http.Handler) *httptest.Server {
+	t.Helper()
+
+	ts := httptest.NewServer(handler)
+	t.Cleanup(ts.Close)
+
+	originalBaseURL := GitHubBaseURL
+	GitHubBaseURL = ts.URL
+	t.Cleanup(func() { GitHubBaseURL = originalBaseURL })
+	return ts
+}
 
-	originalPluginDir := PluginDir
-	PluginDir = tempDir
-	defer func() { PluginDir = originalPluginDir }()
+func TestEnsureProvider_NewInstall(t *testing.T) {
+	usePluginDir(t)
 
 	var ts *httptest.Server
-	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	ts = useGitHubServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path == "/download/binary" {
 			w.Write([]byte("I AM A BINARY CONTENT"))
 			return
 		}
+		if r.URL.Path == "/download/checksums.txt" {
+			w.Write([]byte(checksumsFor("I AM A BINARY CONTENT")))
+			return
+		}
 
 		w.Header().Set("Content-Type", "application/json")
 		w.Write([]byte(mockGitHubResponse(ts.URL+"/download/binary", "v1.0.0")))
 	}))
-	defer ts.Close()
-
-	originalBaseURL := GitHubBaseURL
-	GitHubBaseURL = ts.URL
-	defer func() { GitHubBaseURL = originalBaseURL }()
 
-	path, err := EnsureProvider("test", "github.com/fake/repo", "latest")
+	path, err := EnsureProvider(testSpec)
 	if err != nil {
 		t.Fatalf("EnsureProvider failed: %v", err)
 	}
@@ -80,13 +101,7 @@ func TestEnsureProvider_NewInstall(t *testing.T) {
 }
 
 func TestEnsureProvider_AlreadyUpToDate(t *testing.T) {
-	tempDir, err := os.MkdirTemp("", "gohl-plugins")
-	if err != nil {
-		t.Fatal(err)
-	}
-	defer os.RemoveAll(tempDir)
-	PluginDir = tempDir
-	defer func() { PluginDir = "./plugins" }()
+	tempDir := usePluginDir(t)
 
 	binaryName := "provider-test"
 	if runtime.GOOS == "windows" {
@@ -100,20 +115,20 @@ func TestEnsureProvider_AlreadyUpToDate(t *testing.T) {
 	downloadHit := false
 
 	var ts *httptest.Server
-	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	ts = useGitHubServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path == "/download/binary" {
 			downloadHit = true
 			w.Write([]byte("NEW BINARY"))
 			return
 		}
+		if r.URL.Path == "/download/checksums.txt" {
+			w.Write([]byte(checksumsFor("OLD BINARY")))
+			return
+		}
 		w.Write([]byte(mockGitHubResponse(ts.URL+"/download/binary", "v1.0.0")))
 	}))
-	defer ts.Close()
-
-	GitHubBaseURL = ts.URL
-	defer func() { GitHubBaseURL = "https://api.github.com" }()
 
-	_, err = EnsureProvider("test", "github.com/fake/repo", "latest")
+	_, err := EnsureProvider(testSpec)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -124,13 +139,7 @@ func TestEnsureProvider_AlreadyUpToDate(t *testing.T) {
 }
 
 func TestEnsureProvider_UpdateNeeded(t *testing.T) {
-	tempDir, err := os.MkdirTemp("", "gohl-plugins")
-	if err != nil {
-		t.Fatal(err)
-	}
-	defer os.RemoveAll(tempDir)
-	PluginDir = tempDir
-	defer func() { PluginDir = "./plugins" }()
+	tempDir := usePluginDir(t)
 
 	binaryName := "provider-test"
 	if runtime.GOOS == "windows" {
@@ -142,19 +151,19 @@ func TestEnsureProvider_UpdateNeeded(t *testing.T) {
 	os.WriteFile(localPath+".version", []byte("v0.9.0"), 0644)
 
 	var ts *httptest.Server
-	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	ts = useGitHubServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path == "/download/binary" {
 			w.Write([]byte("NEW BINARY"))
 			return
 		}
+		if r.URL.Path == "/download/checksums.txt" {
+			w.Write([]byte(checksumsFor("NEW BINARY")))
+			return
+		}
 		w.Write([]byte(mockGitHubResponse(ts.URL+"/download/binary", "v1.0.0")))
 	}))
-	defer ts.Close()
-
-	GitHubBaseURL = ts.URL
-	defer func() { GitHubBaseURL = "https://api.github.com" }()
 
-	_, err = EnsureProvider("test", "github.com/fake/repo", "latest")
+	_, err := EnsureProvider(testSpec)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -169,3 +178,290 @@ func TestEnsureProvider_UpdateNeeded(t *testing.T) {
 		t.Error("Version file was not updated")
 	}
 }
+
+func TestEnsureProvider_ChecksumMismatch(t *testing.T) {
+	tempDir := usePluginDir(t)
+
+	var ts *httptest.Server
+	ts = useGitHubServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/download/binary" {
+			w.Write([]byte("TAMPERED BINARY"))
+			return
+		}
+		if r.URL.Path == "/download/checksums.txt" {
+			w.Write([]byte(checksumsFor("GENUINE BINARY")))
+			return
+		}
+		w.Write([]byte(mockGitHubResponse(ts.URL+"/download/binary", "v1.0.0")))
+	}))
+
+	_, err := EnsureProvider(testSpec)
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("Expected checksum mismatch error, got %v", err)
+	}
+
+	entries, _ := os.ReadDir(tempDir)
+	for _, entry := range entries {
+		if entry.Name() != installLockName {
+			t.Errorf("Plugin dir should be empty after a rejected download, found %s", entry.Name())
+		}
+	}
+}
+
+func TestEnsureProvider_TamperedCacheIsReinstalled(t *testing.T) {
+	tempDir := usePluginDir(t)
+
+	localPath := filepath.Join(tempDir, "provider-test")
+	if runtime.GOOS == "windows" {
+		localPath += ".exe"
+	}
+
+	genuine := sha256.Sum256([]byte("GENUINE BINARY"))
+	os.WriteFile(localPath, []byte("TAMPERED BINARY"), 0755)
+	os.WriteFile(localPath+".version", []byte("v1.0.0"), 0644)
+	os.WriteFile(localPath+".sha256", []byte(hex.EncodeToString(genuine[:])), 0644)
+
+	var ts *httptest.Server
+	ts = useGitHubServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/download/binary" {
+			w.Write([]byte("GENUINE BINARY"))
+			return
+		}
+		if r.URL.Path == "/download/checksums.txt" {
+			w.Write([]byte(checksumsFor("GENUINE BINARY")))
+			return
+		}
+		w.Write([]byte(mockGitHubResponse(ts.URL+"/download/binary", "v1.0.0")))
+	}))
+
+	if _, err := EnsureProvider(testSpec); err != nil {
+		t.Fatal(err)
+	}
+
+	content, _ := os.ReadFile(localPath)
+	if string(content) != "GENUINE BINARY" {
+		t.Errorf("Tampered binary was not replaced, got: %s", string(content))
+	}
+}
+
+func TestParseChecksums(t *testing.T) {
+	input := "aaa  other_linux_amd64\nBBB *provider-test_linux_amd64\n"
+
+	digest, err := parseChecksums(strings.NewReader(input), "provider-test_linux_amd64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if digest != "bbb" {
+		t.Errorf("Wrong digest: %s", digest)
+	}
+
+	if _, err := parseChecksums(strings.NewReader(input), "missing"); err == nil {
+		t.Error("Expected error for asset missing from checksums")
+	}
+}
+
+func TestEnsureProvider_Offline(t *testing.T) {
+	tempDir := usePluginDir(t)
+
+	useGitHubServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Offline mode made a request to %s", r.URL.Path)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+
+	offline := testSpec
+	offline.Offline = true
+
+	_, err := EnsureProvider(offline)
+	if err == nil || !strings.Contains(err.Error(), "offline mode") {
+		t.Fatalf("Expected offline error without a cached binary, got %v", err)
+	}
+
+	localPath := filepath.Join(tempDir, "provider-test")
+	if runtime.GOOS == "windows" {
+		localPath += ".exe"
+	}
+	digest := sha256.Sum256([]byte("CACHED BINARY"))
+	os.WriteFile(localPath, []byte("CACHED BINARY"), 0755)
+	os.WriteFile(localPath+".version", []byte("v1.0.0"), 0644)
+
+	// Installs from before digest files existed cannot be verified offline.
+	_, err = EnsureProvider(offline)
+	if err == nil || !strings.Contains(err.Error(), "without a recorded digest") || !strings.Contains(err.Error(), "gohl providers install") {
+		t.Fatalf("Expected a legacy install error, got %v", err)
+	}
+
+	os.WriteFile(localPath+".sha256", []byte(hex.EncodeToString(digest[:])), 0644)
+
+	path, err := EnsureProvider(offline)
+	if err != nil {
+		t.Fatalf("Expected cached binary to be used offline: %v", err)
+	}
+	if path != localPath {
+		t.Errorf("Wrong path: %s", path)
+	}
+
+	offline.Version = "v2.0.0"
+	if _, err := EnsureProvider(offline); err == nil {
+		t.Error("Expected error when the cached version does not match the pinned version")
+	}
+}
+
+func TestEnsureProvider_PinnedVersionIsCacheFirst(t *testing.T) {
+	tempDir := usePluginDir(t)
+
+	apiHit := false
+	useGitHubServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apiHit = true
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+
+	localPath := filepath.Join(tempDir, "provider-test")
+	if runtime.GOOS == "windows" {
+		localPath += ".exe"
+	}
+	digest := sha256.Sum256([]byte("PINNED BINARY"))
+	os.WriteFile(localPath, []byte("PINNED BINARY"), 0755)
+	os.WriteFile(localPath+".version", []byte("v1.0.0"), 0644)
+	os.WriteFile(localPath+".sha256", []byte(hex.EncodeToString(digest[:])), 0644)
+
+	pinned := testSpec
+	pinned.Version = "v1.0.0"
+	if _, err := EnsureProvider(pinned); err != nil {
+		t.Fatal(err)
+	}
+	if apiHit {
+		t.Error("Pinned and cached provider should not call the releases API")
+	}
+}
+
+func TestEnsureProvider_ConcurrentInstalls(t *testing.T) {
+	tempDir := usePluginDir(t)
+
+	var mu sync.Mutex
+	downloads := 0
+
+	var ts *httptest.Server
+	ts = useGitHubServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/download/binary":
+			mu.Lock()
+			downloads++
+			mu.Unlock()
+			w.Write([]byte("CONCURRENT BINARY"))
+		case "/download/checksums.txt":
+			w.Write([]byte(checksumsFor("CONCURRENT BINARY")))
+		default:
+			w.Write([]byte(mockGitHubResponse(ts.URL+"/download/binary", "v1.0.0")))
+		}
+	}))
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := EnsureProvider(testSpec)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Concurrent install failed: %v", err)
+		}
+	}
+
+	if downloads != 1 {
+		t.Errorf("Expected a single download, got %d", downloads)
+	}
+
+	entries, _ := os.ReadDir(tempDir)
+	for _, entry := range entries {
+		if strings.Contains(entry.Name(), ".download") || strings.Contains(entry.Name(), ".tmp") {
+			t.Errorf("Temporary file left behind: %s", entry.Name())
+		}
+	}
+}
+
+func TestEnsureProvider_InterruptedInstallIsRepaired(t *testing.T) {
+	tempDir := usePluginDir(t)
+
+	localPath := filepath.Join(tempDir, "provider-test")
+	if runtime.GOOS == "windows" {
+		localPath += ".exe"
+	}
+
+	// A previous run died after replacing the binary but before the digest
+	// and version marker were written.
+	os.WriteFile(localPath, []byte("TRUNCATED"), 0755)
+	os.WriteFile(localPath+".version", []byte("v1.0.0"), 0644)
+	complete := sha256.Sum256([]byte("COMPLETE BINARY"))
+	os.WriteFile(localPath+".sha256", []byte(hex.EncodeToString(complete[:])), 0644)
+
+	var ts *httptest.Server
+	ts = useGitHubServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/download/binary":
+			w.Write([]byte("COMPLETE BINARY"))
+		case "/download/checksums.txt":
+			w.Write([]byte(checksumsFor("COMPLETE BINARY")))
+		default:
+			w.Write([]byte(mockGitHubResponse(ts.URL+"/download/binary", "v1.0.0")))
+		}
+	}))
+
+	if _, err := EnsureProvider(testSpec); err != nil {
+		t.Fatal(err)
+	}
+
+	content, _ := os.ReadFile(localPath)
+	if string(content) != "COMPLETE BINARY" {
+		t.Errorf("Truncated binary was trusted: %s", string(content))
+	}
+}
+
+func TestEnsureProvider_SourceChange(t *testing.T) {
+	usePluginDir(t)
+
+	var ts *httptest.Server
+	ts = useGitHubServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old/binary":
+			w.Write([]byte("OLD SOURCE"))
+		case "/old/checksums.txt":
+			w.Write([]byte(checksumsFor("OLD SOURCE")))
+		case "/new/binary":
+			w.Write([]byte("NEW SOURCE"))
+		case "/new/checksums.txt":
+			w.Write([]byte(checksumsFor("NEW SOURCE")))
+		default:
+			if strings.Contains(r.URL.Path, "/fork/") {
+				w.Write([]byte(mockGitHubResponse(ts.URL+"/new/binary", "v1.0.0")))
+				return
+			}
+			w.Write([]byte(mockGitHubResponse(ts.URL+"/old/binary", "v1.0.0")))
+		}
+	}))
+
+	spec := testSpec
+	spec.Version = "v1.0.0"
+	path, err := EnsureProvider(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if readMarker(path+".source") != spec.Source {
+		t.Errorf("Source was not recorded: %q", readMarker(path+".source"))
+	}
+
+	// Same tag, different source: the cached binary must not be served.
+	spec.Source = "github.com/fork/repo"
+	if _, err := EnsureProvider(spec); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "NEW SOURCE" {
+		t.Errorf("Binary from the previous source was reused: %s", string(data))
+	}
+}
@@ -0,0 +1,71 @@
+package registry
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const installLockName = ".install.lock"
+
+// installMu serializes installs inside one process; the file lock does the
+// same across processes, e.g. a cron scan racing a manual one.
+var installMu sync.Mutex
+
+// lockPluginDir takes the install lock, resolving and creating PluginDir
+// first.
+func lockPluginDir() (func(), error) {
+	installMu.Lock()
+	resolvePluginDir()
+
+	if err := os.MkdirAll(PluginDir, 0755); err != nil {
+		installMu.Unlock()
+		return nil, fmt.Errorf("failed to create plugin dir: %v", err)
+	}
+
+	f, err := os.OpenFile(filepath.Join(PluginDir, installLockName), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		installMu.Unlock()
+		return nil, fmt.Errorf("failed to open install lock: %v", err)
+	}
+
+	if err := acquireFileLock(f); err != nil {
+		f.Close()
+		installMu.Unlock()
+		return nil, fmt.Errorf("failed to acquire install lock: %v", err)
+	}
+
+	return func() {
+		releaseFileLock(f)
+		f.Close()
+		installMu.Unlock()
+	}, nil
+}
+
+// writeFileAtomic replaces path by renaming a fully written temporary file
+// over it, so readers never observe a partial file.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,16 @@
+//go:build !windows
+
+package registry
+
+import (
+	"os"
+	"syscall"
+)
+
+func acquireFileLock(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_EX) // #nosec G115 -- fd fits in int
+}
+
+func releaseFileLock(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN) // #nosec G115 -- fd fits in int
+}
@@ -0,0 +1,101 @@
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"sort"
+)
+
+// LockFilePath points at gohl.lock. It is empty by default, which disables
+// locking; the CLI sets it next to the loaded gohl.yaml.
+var LockFilePath = ""
+
+type LockEntry struct {
+	Name      string `json:"name"`
+	Source    string `json:"source"`
+	Requested string `json:"requested"`
+	Version   string `json:"version"`
+	Asset     string `json:"asset"`
+	AssetURL  string `json:"asset_url"`
+	Digest    string `json:"digest"`
+	Signer    string `json:"signer,omitempty"`
+}
+
+type Lock struct {
+	Providers map[string]LockEntry `json:"providers"`
+}
+
+type lockFile struct {
+	Providers []LockEntry `json:"providers"`
+}
+
+func LoadLock() (*Lock, error) {
+	lock := &Lock{Providers: make(map[string]LockEntry)}
+	if LockFilePath == "" {
+		return lock, nil
+	}
+
+	data, err := os.ReadFile(LockFilePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return lock, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var file lockFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+
+	for _, entry := range file.Providers {
+		lock.Providers[entry.Name] = entry
+	}
+	return lock, nil
+}
+
+func (l *Lock) Save() error {
+	if LockFilePath == "" {
+		return nil
+	}
+
+	file := lockFile{Providers: make([]LockEntry, 0, len(l.Providers))}
+	for _, entry := range l.Providers {
+		file.Providers = append(file.Providers, entry)
+	}
+	sort.Slice(file.Providers, func(i, j int) bool {
+		return file.Providers[i].Name < file.Providers[j].Name
+	})
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(LockFilePath, append(data, '\n'), 0644)
+}
+
+// binaryDigest is the digest the installed binary must have. Archives are
+// locked by the digest of the archive, which says nothing about the binary
+// extracted from it.
+func (e LockEntry) binaryDigest() string {
+	if archiveFormat(e.Asset) != "" {
+		return ""
+	}
+	return e.Digest
+}
+
+// lookup returns the locked entry for spec, as long as the configured source
+// and requested version still match what was locked. Providers that require
+// a signature also need the entry to have been verified with their key.
+func (l *Lock) lookup(spec ProviderSpec) (LockEntry, bool) {
+	entry, ok := l.Providers[spec.Name]
+	if !ok || entry.Source != spec.Source || entry.Requested != spec.Version {
+		return LockEntry{}, false
+	}
+	if signer := requiredSigner(spec); signer != "" && entry.Signer != signer {
+		return LockEntry{}, false
+	}
+	return entry, true
+}
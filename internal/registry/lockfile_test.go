@@ -0,0 +1,147 @@
+package registry
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEnsureProvider_LockFile(t *testing.T) {
+	tempDir := usePluginDir(t)
+	LockFilePath = filepath.Join(tempDir, "gohl.lock")
+
+	release := "v1.0.0"
+	content := "BINARY v1"
+	apiHits := 0
+
+	var ts *httptest.Server
+	ts = useGitHubServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/download/binary":
+			w.Write([]byte(content))
+		case "/download/checksums.txt":
+			w.Write([]byte(checksumsFor(content)))
+		default:
+			apiHits++
+			w.Write([]byte(mockGitHubResponse(ts.URL+"/download/binary", release)))
+		}
+	}))
+
+	path, err := EnsureProvider(testSpec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lock, err := LoadLock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry, ok := lock.Providers["test"]
+	if !ok {
+		t.Fatal("Lock entry was not written")
+	}
+	if entry.Version != "v1.0.0" || entry.Requested != "latest" || entry.AssetURL != ts.URL+"/download/binary" || entry.Digest == "" {
+		t.Errorf("Unexpected lock entry: %+v", entry)
+	}
+
+	// A new upstream release must not be picked up while the lock is in place.
+	release, content = "v2.0.0", "BINARY v2"
+	apiHits = 0
+
+	if _, err := EnsureProvider(testSpec); err != nil {
+		t.Fatal(err)
+	}
+	if apiHits != 0 {
+		t.Errorf("Locked provider called the releases API %d times", apiHits)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "BINARY v1" {
+		t.Errorf("Locked binary changed: %s", string(data))
+	}
+
+	update := testSpec
+	update.Update = true
+	if _, err := EnsureProvider(update); err != nil {
+		t.Fatal(err)
+	}
+
+	lock, _ = LoadLock()
+	if lock.Providers["test"].Version != "v2.0.0" {
+		t.Errorf("Update did not refresh the lock: %+v", lock.Providers["test"])
+	}
+	if data, _ := os.ReadFile(path); string(data) != "BINARY v2" {
+		t.Errorf("Update did not install the new binary: %s", string(data))
+	}
+}
+
+func TestLock_SourceChangeInvalidatesEntry(t *testing.T) {
+	lock := &Lock{Providers: map[string]LockEntry{
+		"test": {Name: "test", Source: "github.com/fake/repo", Requested: "latest", Version: "v1.0.0"},
+	}}
+
+	if _, ok := lock.lookup(testSpec); !ok {
+		t.Error("Expected matching entry")
+	}
+
+	moved := testSpec
+	moved.Source = "github.com/other/repo"
+	if _, ok := lock.lookup(moved); ok {
+		t.Error("Entry should not match a different source")
+	}
+
+	pinned := testSpec
+	pinned.Version = "v1.2.0"
+	if _, ok := lock.lookup(pinned); ok {
+		t.Error("Entry should not match a different requested version")
+	}
+}
+
+func TestEnsureProvider_LockedDigestOnCacheHit(t *testing.T) {
+	tempDir := usePluginDir(t)
+	LockFilePath = filepath.Join(tempDir, "gohl.lock")
+
+	checksumHits := 0
+	var ts *httptest.Server
+	ts = useGitHubServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/download/binary":
+			w.Write([]byte("GENUINE BINARY"))
+		case "/download/checksums.txt":
+			checksumHits++
+			w.Write([]byte(checksumsFor("GENUINE BINARY")))
+		default:
+			w.Write([]byte(mockGitHubResponse(ts.URL+"/download/binary", "v1.0.0")))
+		}
+	}))
+
+	path, err := EnsureProvider(testSpec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Confirming the cached binary must not fetch the checksums again.
+	os.Remove(LockFilePath)
+	checksumHits = 0
+	if _, err := EnsureProvider(testSpec); err != nil {
+		t.Fatal(err)
+	}
+	if checksumHits != 0 {
+		t.Errorf("Locking a cached binary fetched the checksums %d times", checksumHits)
+	}
+
+	// A swapped binary with a matching digest file is caught by gohl.lock.
+	swapped := []byte("SWAPPED BINARY")
+	sum := sha256.Sum256(swapped)
+	os.WriteFile(path, swapped, 0755)
+	os.WriteFile(path+".sha256", []byte(hex.EncodeToString(sum[:])), 0644)
+
+	if _, err := EnsureProvider(testSpec); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "GENUINE BINARY" {
+		t.Errorf("Binary that does not match gohl.lock was reused: %s", string(data))
+	}
+}
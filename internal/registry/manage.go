@@ -0,0 +1,310 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/danielvollbro/gohl/internal/digest"
+)
+
+// Installed is a provider binary found in PluginDir.
+type Installed struct {
+	Name    string `json:"name"`
+	Path    string `json:"path"`
+	Version string `json:"version,omitempty"`
+	Digest  string `json:"digest,omitempty"`
+	Size    int64  `json:"size"`
+}
+
+// Verification is the result of checking an installed provider against the
+// digests recorded at install time and in gohl.lock.
+type Verification struct {
+	Name     string   `json:"name"`
+	Path     string   `json:"path"`
+	Version  string   `json:"version,omitempty"`
+	OK       bool     `json:"ok"`
+	Problems []string `json:"problems,omitempty"`
+}
+
+// Status combines a provider's configuration with what is installed for it.
+type Status struct {
+	Descriptor
+	Installed string `json:"installed,omitempty"`
+	Path      string `json:"path,omitempty"`
+	Digest    string `json:"digest,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Statuses describes the named providers plus every installed binary that
+// none of them accounts for; those are reported with OriginCache.
+func Statuses(names []string) ([]Status, error) {
+	installed, err := InstalledProviders()
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]Installed, len(installed))
+	for _, item := range installed {
+		byName[item.Name] = item
+	}
+
+	var statuses []Status
+	seen := make(map[string]bool)
+	for _, name := range names {
+		seen[name] = true
+
+		desc, err := Describe(name)
+		if err != nil {
+			statuses = append(statuses, Status{Descriptor: Descriptor{Name: name}, Error: err.Error()})
+			continue
+		}
+
+		status := Status{Descriptor: desc}
+		switch desc.Origin {
+		case OriginDownload:
+			if item, ok := byName[name]; ok {
+				status.Installed = item.Version
+				status.Path = item.Path
+				status.Digest = item.Digest
+			}
+		case OriginPath:
+			status.Path = desc.Location
+			if sum, err := digest.File(desc.Location); err == nil {
+				status.Digest = sum
+			} else {
+				status.Error = err.Error()
+			}
+		}
+		statuses = append(statuses, status)
+	}
+
+	for _, item := range installed {
+		if seen[item.Name] {
+			continue
+		}
+		statuses = append(statuses, Status{
+			Descriptor: Descriptor{Name: item.Name, Origin: OriginCache, Location: filepath.Dir(item.Path)},
+			Installed:  item.Version,
+			Path:       item.Path,
+			Digest:     item.Digest,
+		})
+	}
+
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses, nil
+}
+
+// InstalledProviders lists the provider binaries on the plugin search path,
+// sorted by name. A provider found in several dirs is reported once, from
+// the first dir that has it.
+func InstalledProviders() ([]Installed, error) {
+	seen := make(map[string]bool)
+	var installed []Installed
+
+	for _, dir := range searchDirs() {
+		entries, err := os.ReadDir(dir)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range entries {
+			fileName := entry.Name()
+			if entry.IsDir() || !strings.HasPrefix(fileName, "provider-") || isMarker(fileName) {
+				continue
+			}
+
+			name := strings.TrimPrefix(fileName, "provider-")
+			if runtime.GOOS == "windows" {
+				name = strings.TrimSuffix(name, ".exe")
+			}
+			if seen[name] {
+				continue
+			}
+
+			if item, err := describeBinary(name, binaryPathIn(dir, name)); err == nil {
+				seen[name] = true
+				installed = append(installed, item)
+			}
+		}
+	}
+
+	sort.Slice(installed, func(i, j int) bool { return installed[i].Name < installed[j].Name })
+	return installed, nil
+}
+
+// InstalledProvider describes the first installed copy of a provider on the
+// plugin search path.
+func InstalledProvider(name string) (Installed, error) {
+	if err := checkName(name); err != nil {
+		return Installed{}, err
+	}
+
+	path, ok := installedPath(name)
+	if !ok {
+		return Installed{}, fmt.Errorf("provider '%s' is not installed in %s", name, strings.Join(searchDirs(), ", "))
+	}
+	return describeBinary(name, path)
+}
+
+func describeBinary(name, path string) (Installed, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return Installed{}, err
+	}
+
+	return Installed{
+		Name:    name,
+		Path:    path,
+		Version: readMarker(path + ".version"),
+		Digest:  readMarker(path + ".sha256"),
+		Size:    info.Size(),
+	}, nil
+}
+
+// markerSuffixes are the files an install keeps next to a provider binary,
+// starting with the version marker that marks the install complete.
+var markerSuffixes = []string{".version", ".sha256", ".source", ".signer"}
+
+func isMarker(fileName string) bool {
+	for _, suffix := range markerSuffixes {
+		if strings.HasSuffix(fileName, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func readMarker(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// InstallProvider makes sure a downloaded provider is installed, honouring
+// gohl.lock, and returns what ended up in PluginDir.
+func InstallProvider(name string) (Installed, error) {
+	desc, err := Describe(name)
+	if err != nil {
+		return Installed{}, err
+	}
+	if desc.Origin != OriginDownload {
+		return Installed{}, fmt.Errorf("provider '%s' is not downloaded (origin: %s)", name, desc.Origin)
+	}
+
+	path, err := EnsureProvider(downloadSpec(desc))
+	if err != nil {
+		return Installed{}, err
+	}
+	return describeBinary(name, path)
+}
+
+// RemoveProvider deletes a provider installed in PluginDir and its marker
+// files. Copies in other search dirs, e.g. SystemPluginDir, belong to
+// whoever put them there and are refused. gohl.lock is left alone, so a
+// later install gets the same version back.
+func RemoveProvider(name string) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
+
+	unlock, err := lockPluginDir()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	path := binaryPath(name)
+	if _, err := os.Stat(path); err != nil {
+		if other, ok := installedPath(name); ok {
+			return fmt.Errorf("provider '%s' is installed in %s, which gohl does not manage", name, filepath.Dir(other))
+		}
+		return fmt.Errorf("provider '%s' is not installed in %s", name, PluginDir)
+	}
+
+	// The version marker goes first, like an install in reverse, so a failed
+	// removal never leaves a binary that looks complete.
+	files := []string{path + ".version", path}
+	for _, suffix := range markerSuffixes[1:] {
+		files = append(files, path+suffix)
+	}
+	for _, file := range files {
+		if err := os.Remove(file); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove %s: %v", file, err)
+		}
+	}
+	return nil
+}
+
+// VerifyProvider checks an installed provider without touching the network:
+// the install must be complete, the binary must match the digest recorded at
+// install time, and a raw binary must match the digest in gohl.lock. It holds
+// the install lock so a concurrent install is not reported as broken.
+func VerifyProvider(name string) (Verification, error) {
+	if err := checkName(name); err != nil {
+		return Verification{}, err
+	}
+
+	unlock, err := lockPluginDir()
+	if err != nil {
+		return Verification{}, err
+	}
+	defer unlock()
+
+	item, err := InstalledProvider(name)
+	if err != nil {
+		return Verification{}, err
+	}
+
+	result := Verification{Name: name, Path: item.Path, Version: item.Version}
+	problem := func(format string, args ...interface{}) {
+		result.Problems = append(result.Problems, fmt.Sprintf(format, args...))
+	}
+
+	if item.Version == "" {
+		problem("no version marker, the install did not complete")
+	}
+
+	actual, err := digest.File(item.Path)
+	if err != nil {
+		return Verification{}, err
+	}
+
+	switch {
+	case item.Digest == "":
+		problem("no recorded digest")
+	case item.Digest != actual:
+		problem("digest mismatch: recorded %s, actual %s", item.Digest, actual)
+	}
+
+	lock, err := LoadLock()
+	if err != nil {
+		return Verification{}, fmt.Errorf("failed to read lock file: %v", err)
+	}
+	if entry, ok := lock.Providers[name]; ok {
+		if item.Version != "" && entry.Version != item.Version {
+			problem("installed version %s, gohl.lock has %s", item.Version, entry.Version)
+		}
+		if digest := entry.binaryDigest(); digest != "" && digest != actual {
+			problem("digest does not match gohl.lock (%s)", entry.Digest)
+		}
+	}
+
+	if runtime.GOOS != "windows" {
+		if info, err := os.Stat(item.Path); err == nil && info.Mode()&0111 == 0 {
+			problem("binary is not executable")
+		}
+	}
+
+	result.OK = len(result.Problems) == 0
+	return result, nil
+}
@@ -0,0 +1,207 @@
+package registry
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func installFake(t *testing.T, name, version string, content []byte) string {
+	t.Helper()
+
+	path := binaryPath(name)
+	if err := os.WriteFile(path, content, 0755); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(content)
+	os.WriteFile(path+".sha256", []byte(hex.EncodeToString(sum[:])), 0644)
+	if version != "" {
+		os.WriteFile(path+".version", []byte(version), 0644)
+	}
+	return path
+}
+
+func usePluginDir(t *testing.T) string {
+	t.Helper()
+
+	tempDir, err := os.MkdirTemp("", "gohl-manage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(tempDir) })
+
+	originalPluginDir, originalLock := PluginDir, LockFilePath
+	PluginDir, LockFilePath = tempDir, ""
+	t.Cleanup(func() { PluginDir, LockFilePath = originalPluginDir, originalLock })
+	return tempDir
+}
+
+func TestInstalledProviders(t *testing.T) {
+	dir := usePluginDir(t)
+
+	installFake(t, "beta", "v2.0.0", []byte("beta"))
+	installFake(t, "alpha", "v1.0.0", []byte("alpha"))
+	os.WriteFile(filepath.Join(dir, ".provider-alpha.download-123"), []byte("partial"), 0644)
+	os.WriteFile(filepath.Join(dir, installLockName), nil, 0644)
+
+	installed, err := InstalledProviders()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(installed) != 2 || installed[0].Name != "alpha" || installed[1].Name != "beta" {
+		t.Fatalf("unexpected providers: %+v", installed)
+	}
+	if installed[0].Version != "v1.0.0" || installed[0].Size != 5 || len(installed[0].Digest) != 64 {
+		t.Errorf("incomplete details: %+v", installed[0])
+	}
+}
+
+func TestVerifyProvider(t *testing.T) {
+	usePluginDir(t)
+
+	installFake(t, "good", "v1.0.0", []byte("good"))
+	tampered := installFake(t, "tampered", "v1.0.0", []byte("original"))
+	os.WriteFile(tampered, []byte("evil"), 0755)
+	installFake(t, "interrupted", "", []byte("half"))
+
+	if result, err := VerifyProvider("good"); err != nil || !result.OK {
+		t.Errorf("expected good provider to verify, got %+v, %v", result, err)
+	}
+
+	result, err := VerifyProvider("tampered")
+	if err != nil || result.OK || !strings.Contains(strings.Join(result.Problems, ";"), "digest mismatch") {
+		t.Errorf("expected digest mismatch, got %+v, %v", result, err)
+	}
+
+	result, err = VerifyProvider("interrupted")
+	if err != nil || result.OK {
+		t.Errorf("expected incomplete install to fail, got %+v, %v", result, err)
+	}
+
+	if _, err := VerifyProvider("missing"); err == nil {
+		t.Error("expected an error for a provider that is not installed")
+	}
+}
+
+func TestVerifyProvider_LockMismatch(t *testing.T) {
+	dir := usePluginDir(t)
+	LockFilePath = filepath.Join(dir, "gohl.lock")
+
+	installFake(t, "locked", "v1.0.0", []byte("binary"))
+
+	lock, _ := LoadLock()
+	lock.Providers["locked"] = LockEntry{Name: "locked", Version: "v1.1.0", Asset: "provider-locked", Digest: strings.Repeat("0", 64)}
+	if err := lock.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := VerifyProvider("locked")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.OK || len(result.Problems) != 2 {
+		t.Errorf("expected version and digest problems against gohl.lock, got %+v", result)
+	}
+}
+
+func TestRemoveProvider(t *testing.T) {
+	dir := usePluginDir(t)
+
+	installFake(t, "gone", "v1.0.0", []byte("gone"))
+	installFake(t, "kept", "v1.0.0", []byte("kept"))
+
+	if err := RemoveProvider("gone"); err != nil {
+		t.Fatalf("RemoveProvider failed: %v", err)
+	}
+	if err := RemoveProvider("gone"); err == nil {
+		t.Error("expected removing a missing provider to fail")
+	}
+
+	entries, _ := os.ReadDir(dir)
+	for _, entry := range entries {
+		if strings.Contains(entry.Name(), "gone") {
+			t.Errorf("left behind %s", entry.Name())
+		}
+	}
+	if _, err := os.Stat(binaryPath("kept")); err != nil {
+		t.Errorf("removed the wrong provider: %v", err)
+	}
+}
+
+func TestProviderNameValidation(t *testing.T) {
+	dir := usePluginDir(t)
+
+	outside := filepath.Join(filepath.Dir(dir), "provider-escape")
+	os.WriteFile(outside, []byte("not ours"), 0755)
+	t.Cleanup(func() { os.Remove(outside) })
+
+	for _, name := range []string{"", "../escape", "..", "nested/name", `nested\name`} {
+		if err := RemoveProvider(name); err == nil || !strings.Contains(err.Error(), "invalid provider name") {
+			t.Errorf("RemoveProvider(%q): expected invalid name, got %v", name, err)
+		}
+		if _, err := VerifyProvider(name); err == nil {
+			t.Errorf("VerifyProvider(%q): expected an error", name)
+		}
+		if _, err := EnsureProvider(ProviderSpec{Name: name, Source: "github.com/owner/repo", Version: "v1.0.0"}); err == nil {
+			t.Errorf("EnsureProvider(%q): expected an error", name)
+		}
+	}
+
+	if _, err := os.Stat(outside); err != nil {
+		t.Errorf("file outside the plugin dir was touched: %v", err)
+	}
+}
+
+func TestStatuses(t *testing.T) {
+	usePluginDir(t)
+	viper.Reset()
+
+	installFake(t, "proxmox", "v1.2.0", []byte("proxmox"))
+	installFake(t, "leftover", "v0.1.0", []byte("leftover"))
+
+	localPath := filepath.Join(t.TempDir(), "local-provider")
+	os.WriteFile(localPath, []byte("local"), 0755)
+
+	viper.Set("proxmox.source", "github.com/fake/provider-proxmox")
+	viper.Set("proxmox.version", "v1.2.0")
+	viper.Set("local.path", localPath)
+
+	statuses, err := Statuses([]string{"proxmox", "local", "unknown"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	byName := make(map[string]Status)
+	for _, status := range statuses {
+		byName[status.Name] = status
+	}
+
+	if s := byName["proxmox"]; s.Origin != OriginDownload || s.Installed != "v1.2.0" || s.Digest == "" {
+		t.Errorf("unexpected download status: %+v", s)
+	}
+	if s := byName["local"]; s.Origin != OriginPath || s.Path != localPath || len(s.Digest) != 64 {
+		t.Errorf("unexpected path status: %+v", s)
+	}
+	if s := byName["leftover"]; s.Origin != OriginCache || s.Installed != "v0.1.0" {
+		t.Errorf("unconfigured binary not reported: %+v", s)
+	}
+	if s := byName["unknown"]; s.Error == "" {
+		t.Errorf("expected an error for an unknown provider: %+v", s)
+	}
+}
+
+func TestInstalledProviders_NoPluginDir(t *testing.T) {
+	usePluginDir(t)
+	PluginDir = filepath.Join(PluginDir, "missing")
+
+	installed, err := InstalledProviders()
+	if err != nil || len(installed) != 0 {
+		t.Errorf("expected no providers and no error, got %v, %v", installed, err)
+	}
+}
@@ -0,0 +1,152 @@
+package registry
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+const (
+	// SystemPluginDir holds providers installed by a package manager or an
+	// administrator for every user.
+	SystemPluginDir = "/usr/lib/gohl/plugins"
+
+	// LegacyPluginDir is where providers were installed before the search
+	// path existed. It is still searched so those installs keep working.
+	LegacyPluginDir = "./plugins"
+)
+
+// PluginDirs is the search path for installed providers, in order. Installs
+// always go to PluginDir, which is pointed at the first writable entry once
+// something needs to write to it. An empty search path means PluginDir only.
+var PluginDirs []string
+
+// pluginDirPending is set by SetPluginDirs until PluginDir is resolved, so
+// commands that only read the search path never create or probe dirs.
+var pluginDirPending bool
+
+// DefaultPluginDirs returns the search path: the configured plugin_dir if
+// any, then the per-user data dir, the legacy ./plugins dir and the
+// system-wide dir.
+func DefaultPluginDirs(configured string) []string {
+	var dirs []string
+	if configured != "" {
+		dirs = append(dirs, configured)
+	}
+	if dir := userDataDir(); dir != "" {
+		dirs = append(dirs, filepath.Join(dir, "gohl", "plugins"))
+	}
+	dirs = append(dirs, LegacyPluginDir)
+	if runtime.GOOS != "windows" {
+		dirs = append(dirs, SystemPluginDir)
+	}
+	return dirs
+}
+
+// userDataDir follows the XDG base directory spec, falling back to
+// ~/.local/share, and uses %LocalAppData% on Windows.
+func userDataDir() string {
+	if runtime.GOOS == "windows" {
+		return os.Getenv("LocalAppData")
+	}
+	if dir := os.Getenv("XDG_DATA_HOME"); filepath.IsAbs(dir) {
+		return dir
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(home, ".local", "share")
+	}
+	return ""
+}
+
+// SetPluginDirs installs dirs as the search path. The install location is
+// picked by resolvePluginDir on the first install, not here.
+func SetPluginDirs(dirs []string) {
+	PluginDirs = dirs
+	if len(dirs) == 0 {
+		return
+	}
+
+	PluginDir = dirs[0]
+	pluginDirPending = true
+}
+
+// resolvePluginDir makes the first writable search dir the install
+// location. If none is writable the first entry is kept, so installs fail
+// with a useful error. Callers hold installMu.
+func resolvePluginDir() {
+	if !pluginDirPending {
+		return
+	}
+	pluginDirPending = false
+
+	for _, dir := range PluginDirs {
+		if writable(dir) {
+			PluginDir = dir
+			return
+		}
+	}
+}
+
+func writable(dir string) bool {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return false
+	}
+
+	probe, err := os.CreateTemp(dir, ".write-test-*")
+	if err != nil {
+		return false
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+	return true
+}
+
+// searchDirs is PluginDirs with PluginDir added if it is not part of it.
+func searchDirs() []string {
+	dirs := PluginDirs
+	for _, dir := range dirs {
+		if filepath.Clean(dir) == filepath.Clean(PluginDir) {
+			return dirs
+		}
+	}
+	return append([]string{PluginDir}, dirs...)
+}
+
+// findCached returns the first installed copy of a provider, in search
+// order, that matches want, see cachedBinaryMatches.
+func findCached(name string, want cacheWant) (string, bool) {
+	for _, dir := range searchDirs() {
+		path := binaryPathIn(dir, name)
+		if cachedBinaryMatches(path, want) {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// findUnverified returns an install of the wanted version that predates
+// digest files. Those are only verified against the release checksums, which
+// needs the network.
+func findUnverified(name string, want cacheWant) (string, bool) {
+	for _, dir := range searchDirs() {
+		path := binaryPathIn(dir, name)
+		if _, err := os.Stat(path + ".sha256"); !os.IsNotExist(err) {
+			continue
+		}
+		if _, err := os.Stat(path); err == nil && cachedVersionMatches(path, want) {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// installedPath returns the first copy of a provider binary in search order.
+func installedPath(name string) (string, bool) {
+	for _, dir := range searchDirs() {
+		path := binaryPathIn(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,178 @@
+package registry
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestDefaultPluginDirs(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("XDG paths do not apply on windows")
+	}
+	t.Setenv("XDG_DATA_HOME", "/data")
+
+	dirs := DefaultPluginDirs("/etc/gohl/plugins")
+	want := []string{"/etc/gohl/plugins", "/data/gohl/plugins", LegacyPluginDir, SystemPluginDir}
+	if len(dirs) != len(want) {
+		t.Fatalf("got %v, want %v", dirs, want)
+	}
+	for i := range want {
+		if dirs[i] != want[i] {
+			t.Errorf("dir %d: got %s, want %s", i, dirs[i], want[i])
+		}
+	}
+
+	t.Setenv("XDG_DATA_HOME", "relative/ignored")
+	t.Setenv("HOME", "/home/lab")
+	if dirs := DefaultPluginDirs(""); dirs[0] != "/home/lab/.local/share/gohl/plugins" {
+		t.Errorf("expected ~/.local/share fallback first, got %v", dirs)
+	}
+}
+
+func TestSetPluginDirs_FirstWritable(t *testing.T) {
+	usePluginDir(t)
+	t.Cleanup(func() { PluginDirs, pluginDirPending = nil, false })
+
+	base := t.TempDir()
+	blocker := filepath.Join(base, "file")
+	os.WriteFile(blocker, []byte("not a dir"), 0644)
+
+	unwritable := filepath.Join(blocker, "plugins")
+	user := filepath.Join(base, "user", "plugins")
+	system := filepath.Join(base, "system")
+
+	SetPluginDirs([]string{unwritable, user, system})
+
+	// Nothing is probed or created until something is installed.
+	if _, err := os.Stat(user); !os.IsNotExist(err) {
+		t.Errorf("SetPluginDirs created %s: %v", user, err)
+	}
+
+	unlock, err := lockPluginDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	unlock()
+
+	if PluginDir != user {
+		t.Errorf("expected installs to go to %s, got %s", user, PluginDir)
+	}
+	if len(PluginDirs) != 3 {
+		t.Errorf("search path changed: %v", PluginDirs)
+	}
+}
+
+func TestEnsureProvider_SearchPath(t *testing.T) {
+	usePluginDir(t)
+	t.Cleanup(func() { PluginDirs = nil })
+
+	system := t.TempDir()
+	PluginDirs = []string{PluginDir, system}
+
+	// A pinned version present in the system dir is used without touching
+	// the network, which the bogus source would fail on.
+	original := PluginDir
+	PluginDir = system
+	installFake(t, "packaged", "v1.0.0", []byte("packaged"))
+	PluginDir = original
+
+	spec := ProviderSpec{Name: "packaged", Source: "gitlab://invalid.invalid/x/y", Version: "v1.0.0"}
+	path, err := EnsureProvider(spec)
+	if err != nil {
+		t.Fatalf("EnsureProvider failed: %v", err)
+	}
+	if path != binaryPathIn(system, "packaged") {
+		t.Errorf("expected system copy, got %s", path)
+	}
+
+	// A different version in the user dir shadows it in listings.
+	installFake(t, "packaged", "v2.0.0", []byte("newer"))
+
+	installed, err := InstalledProviders()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(installed) != 1 || installed[0].Version != "v2.0.0" {
+		t.Errorf("expected the user dir copy to shadow the system one, got %+v", installed)
+	}
+
+	path, err = EnsureProvider(spec)
+	if err != nil || path != binaryPathIn(system, "packaged") {
+		t.Errorf("pinned v1.0.0 should still resolve to the system copy, got %s, %v", path, err)
+	}
+}
+
+func TestRemoveProvider_OnlyFromPluginDir(t *testing.T) {
+	usePluginDir(t)
+	t.Cleanup(func() { PluginDirs = nil })
+
+	system := t.TempDir()
+	PluginDirs = []string{PluginDir, system}
+
+	original := PluginDir
+	PluginDir = system
+	packaged := installFake(t, "packaged", "v1.0.0", []byte("packaged"))
+	PluginDir = original
+
+	err := RemoveProvider("packaged")
+	if err == nil || !strings.Contains(err.Error(), "does not manage") {
+		t.Errorf("expected removal from another dir to be refused, got %v", err)
+	}
+	if _, err := os.Stat(packaged); err != nil {
+		t.Errorf("system copy was removed: %v", err)
+	}
+}
+
+func TestEnsureProvider_SearchPathHonoursLock(t *testing.T) {
+	dir := usePluginDir(t)
+	LockFilePath = filepath.Join(dir, "gohl.lock")
+	t.Cleanup(func() { PluginDirs = nil })
+
+	system := t.TempDir()
+	PluginDirs = []string{PluginDir, system}
+
+	var ts *httptest.Server
+	ts = useGitHubServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/download/binary":
+			w.Write([]byte("GENUINE BINARY"))
+		case "/download/checksums.txt":
+			w.Write([]byte(checksumsFor("GENUINE BINARY")))
+		default:
+			w.Write([]byte(mockGitHubResponse(ts.URL+"/download/binary", "v1.0.0")))
+		}
+	}))
+
+	path, err := EnsureProvider(testSpec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	RemoveProvider(testSpec.Name)
+
+	// A copy of the locked version in another dir that does not match the
+	// digest in gohl.lock is not used.
+	swapped := []byte("SWAPPED BINARY")
+	sum := sha256.Sum256(swapped)
+	other := binaryPathIn(system, testSpec.Name)
+	os.WriteFile(other, swapped, 0755)
+	os.WriteFile(other+".sha256", []byte(hex.EncodeToString(sum[:])), 0644)
+	os.WriteFile(other+".version", []byte("v1.0.0"), 0644)
+
+	got, err := EnsureProvider(testSpec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != path {
+		t.Errorf("expected a fresh install in %s, got %s", path, got)
+	}
+	if data, _ := os.ReadFile(got); string(data) != "GENUINE BINARY" {
+		t.Errorf("binary that does not match gohl.lock was used: %s", string(data))
+	}
+}
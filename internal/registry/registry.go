@@ -3,47 +3,274 @@ package registry
 import (
 	"fmt"
 	"os"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/spf13/viper"
 
 	"github.com/danielvollbro/gohl/internal/provider/binary"
+	"github.com/danielvollbro/gohl/internal/provider/remote"
+	"github.com/danielvollbro/gohl/internal/sandbox"
 	"github.com/danielvollbro/gohl/pkg/plugin"
 )
 
-func GetProvider(name string) (plugin.Scanner, error) {
+// Origin describes where a provider is resolved from. When a name matches
+// several origins, a configured source wins over a path, which in turn wins
+// over a scanner compiled into gohl. OriginCache marks a binary
+// left in PluginDir that is no longer configured.
+type Origin string
+
+const (
+	OriginPath     Origin = "path"
+	OriginDownload Origin = "download"
+	OriginBuiltin  Origin = "builtin"
+	OriginCache    Origin = "cache"
+)
+
+type Factory func() plugin.Scanner
+
+type Descriptor struct {
+	Name     string `json:"name"`
+	Origin   Origin `json:"origin"`
+	Location string `json:"location"`
+	Version  string `json:"version,omitempty"`
+}
+
+var (
+	builtinsMu sync.RWMutex
+	builtins   = make(map[string]Factory)
+)
+
+func Register(name string, factory Factory) {
+	builtinsMu.Lock()
+	defer builtinsMu.Unlock()
+
+	if _, exists := builtins[name]; exists {
+		panic(fmt.Sprintf("registry: provider '%s' registered twice", name))
+	}
+	builtins[name] = factory
+}
+
+func Builtins() []string {
+	builtinsMu.RLock()
+	defer builtinsMu.RUnlock()
+
+	names := make([]string, 0, len(builtins))
+	for name := range builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func builtin(name string) (Factory, bool) {
+	builtinsMu.RLock()
+	defer builtinsMu.RUnlock()
+
+	factory, ok := builtins[name]
+	return factory, ok
+}
+
+func Describe(name string) (Descriptor, error) {
 	path := viper.GetString(name + ".path")
 	source := viper.GetString(name + ".source")
 	version := viper.GetString(name + ".version")
 
-	if source != "" {
+	switch {
+	case source != "":
 		if version == "" {
 			version = "latest"
 		}
+		return Descriptor{Name: name, Origin: OriginDownload, Location: source, Version: version}, nil
+	case path != "":
+		return Descriptor{Name: name, Origin: OriginPath, Location: path}, nil
+	}
+
+	if _, ok := builtin(name); ok {
+		return Descriptor{Name: name, Origin: OriginBuiltin, Location: "compiled-in", Version: "builtin"}, nil
+	}
+
+	return Descriptor{}, fmt.Errorf("provider '%s' configuration missing 'source' or 'path'", name)
+}
+
+func GetProvider(name string) (plugin.Scanner, error) {
+	desc, err := Describe(name)
+	if err != nil {
+		return nil, err
+	}
 
-		downloadedPath, err := EnsureProvider(name, source, version)
+	switch desc.Origin {
+	case OriginBuiltin:
+		factory, _ := builtin(name)
+		return factory(), nil
+	case OriginDownload:
+		downloadedPath, err := EnsureProvider(downloadSpec(desc))
 		if err != nil {
 			return nil, fmt.Errorf("failed to download provider '%s': %w", name, err)
 		}
+		desc.Location = downloadedPath
+	}
+
+	if _, err := os.Stat(desc.Location); err != nil {
+		return nil, fmt.Errorf("binary not found at path: %s", desc.Location)
+	}
 
-		path = downloadedPath
+	profile, err := sandboxProfile(desc)
+	if err != nil {
+		return nil, err
 	}
 
-	if path != "" {
-		if _, err := os.Stat(path); err == nil {
-			return binary.New(name, path), nil
+	switch transport := viper.GetString(name + ".transport"); transport {
+	case "", "exec":
+	case "rpc":
+		provider := remote.New(name, desc.Location)
+		provider.Sandbox = profile
+		if interval := viper.GetDuration(name + ".ping_interval"); interval > 0 {
+			provider.PingInterval = interval
 		}
-		return nil, fmt.Errorf("binary not found at path: %s", path)
+		track(provider)
+		return provider, nil
+	default:
+		return nil, fmt.Errorf("provider '%s': unknown transport '%s'", name, transport)
 	}
 
-	return nil, fmt.Errorf("provider '%s' configuration missing 'source' or 'path'", name)
+	provider := binary.New(name, desc.Location)
+	provider.LabID = viper.GetString("lab_id")
+	provider.Settings = GetSettings(name)
+	provider.Checks = viper.GetStringSlice(name + ".checks")
+	provider.UseHandshake = viper.GetBool(name + ".handshake")
+	provider.Sandbox = profile
+	return provider, nil
+}
+
+// sandboxProfile reads the provider's sandbox section. Downloaded providers
+// are sandboxed with the defaults unless it says "sandbox: false"; binaries
+// configured by path only when a sandbox section is present.
+func sandboxProfile(desc Descriptor) (*sandbox.Profile, error) {
+	key := desc.Name + ".sandbox"
+
+	switch value := viper.Get(key).(type) {
+	case nil:
+		if desc.Origin == OriginDownload {
+			return &sandbox.Profile{}, nil
+		}
+		return nil, nil
+	case bool:
+		if value {
+			return &sandbox.Profile{}, nil
+		}
+		return nil, nil
+	case map[string]interface{}:
+		if viper.IsSet(key+".enabled") && !viper.GetBool(key+".enabled") {
+			return nil, nil
+		}
+	default:
+		return nil, fmt.Errorf("provider '%s': 'sandbox' must be a boolean or a section", desc.Name)
+	}
+
+	return &sandbox.Profile{
+		Env:        viper.GetStringSlice(key + ".env"),
+		User:       viper.GetString(key + ".user"),
+		CPUTime:    viper.GetDuration(key + ".cpu_time"),
+		MaxMemory:  uint64(viper.GetSizeInBytes(key + ".max_memory")),
+		MaxOutput:  uint64(viper.GetSizeInBytes(key + ".max_output")),
+		Namespaces: viper.GetStringSlice(key + ".namespaces"),
+		Seccomp:    viper.GetBool(key + ".seccomp"),
+	}, nil
+}
+
+var (
+	runningMu sync.Mutex
+	running   []*remote.Provider
+)
+
+func track(provider *remote.Provider) {
+	runningMu.Lock()
+	defer runningMu.Unlock()
+	running = append(running, provider)
+}
+
+// Shutdown stops the long-running plugins started by GetProvider.
+func Shutdown() {
+	runningMu.Lock()
+	providers := running
+	running = nil
+	runningMu.Unlock()
+
+	for _, provider := range providers {
+		provider.Close()
+	}
+}
+
+// UpdateProvider re-resolves a downloaded provider, ignoring gohl.lock, and
+// records the result as the new locked version.
+func UpdateProvider(name string) (Installed, error) {
+	desc, err := Describe(name)
+	if err != nil {
+		return Installed{}, err
+	}
+
+	if desc.Origin != OriginDownload {
+		return Installed{}, fmt.Errorf("provider '%s' is not downloaded (origin: %s)", name, desc.Origin)
+	}
+
+	spec := downloadSpec(desc)
+	spec.Update = true
+	path, err := EnsureProvider(spec)
+	if err != nil {
+		return Installed{}, err
+	}
+	return describeBinary(name, path)
+}
+
+func downloadSpec(desc Descriptor) ProviderSpec {
+	return ProviderSpec{
+		Name:             desc.Name,
+		Source:           desc.Location,
+		Version:          desc.Version,
+		Binary:           viper.GetString(desc.Name + ".binary"),
+		PublicKey:        viper.GetString(desc.Name + ".public_key"),
+		RequireSignature: viper.GetBool(desc.Name + ".require_signature"),
+		Offline:          viper.GetBool("offline"),
+	}
+}
+
+// agentKeys are provider settings consumed by gohl itself. They are not part
+// of the config handed to providers over the stdin protocol.
+var agentKeys = map[string]bool{
+	"path":              true,
+	"source":            true,
+	"version":           true,
+	"binary":            true,
+	"public_key":        true,
+	"require_signature": true,
+	"timeout":           true,
+	"checks":            true,
+	"transport":         true,
+	"ping_interval":     true,
+	"sandbox":           true,
+	"handshake":         true,
+}
+
+// GetSettings returns the provider's section of gohl.yaml with its nested
+// structure intact, minus the keys gohl uses to locate and run it.
+func GetSettings(providerName string) map[string]interface{} {
+	settings := make(map[string]interface{})
+	for key, value := range viper.GetStringMap(providerName) {
+		if !agentKeys[key] {
+			settings[key] = value
+		}
+	}
+	return settings
 }
 
+// GetConfig flattens the provider's settings into strings for builtin
+// providers and the GOHL_CONFIG_* environment of legacy binaries.
 func GetConfig(providerName string) map[string]string {
-	rawConfig := viper.GetStringMap(providerName)
 	cleanConfig := make(map[string]string)
 
-	for key, value := range rawConfig {
+	for key, value := range GetSettings(providerName) {
 		switch v := value.(type) {
 		case string:
 			cleanConfig[key] = v
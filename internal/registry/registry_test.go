@@ -1,12 +1,20 @@
 package registry
 
 import (
+	"context"
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/spf13/viper"
+
+	"github.com/danielvollbro/gohl/internal/provider/binary"
+	"github.com/danielvollbro/gohl/internal/sandbox"
+	"github.com/danielvollbro/gohl/pkg/plugin"
 )
 
 func TestGetProvider_LocalPath(t *testing.T) {
@@ -66,3 +74,201 @@ func TestGetConfig_Parsing(t *testing.T) {
 		}
 	}
 }
+
+func TestGetProvider_AgentKeysNotPassed(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell script providers are not supported on windows")
+	}
+
+	dir := t.TempDir()
+	original := binary.HandshakeCacheDir
+	binary.HandshakeCacheDir = filepath.Join(dir, "handshakes")
+	t.Cleanup(func() { binary.HandshakeCacheDir = original })
+
+	requestFile := filepath.Join(dir, "request.json")
+	envFile := filepath.Join(dir, "env.txt")
+	script := filepath.Join(dir, "provider")
+	os.WriteFile(script, []byte(`#!/bin/sh
+if [ "$1" = "--gohl-info" ]; then
+  echo '{"protocols": [1]}'
+  exit 0
+fi
+cat > "`+requestFile+`"
+env > "`+envFile+`"
+echo '{"plugin_id": "keys", "checks": []}'
+`), 0755)
+
+	viper.Reset()
+	for _, name := range []string{"modern", "legacy"} {
+		viper.Set(name+".path", script)
+		viper.Set(name+".url", "https://pve.lab")
+		viper.Set(name+".public_key", "key")
+		viper.Set(name+".timeout", "1m")
+	}
+	viper.Set("modern.handshake", true)
+
+	run := func(name string) {
+		t.Helper()
+		p, err := GetProvider(name)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if _, err := p.Analyze(context.Background(), GetConfig(name)); err != nil {
+			t.Fatalf("%s: Analyze failed: %v", name, err)
+		}
+	}
+
+	run("modern")
+	data, err := os.ReadFile(requestFile)
+	if err != nil {
+		t.Fatalf("Provider did not receive a request on stdin: %v", err)
+	}
+	var request binary.Request
+	if err := json.Unmarshal(data, &request); err != nil {
+		t.Fatalf("Invalid request json: %v", err)
+	}
+	if request.Config["url"] != "https://pve.lab" {
+		t.Errorf("Provider setting missing from the request: %v", request.Config)
+	}
+	for _, key := range []string{"path", "public_key", "timeout", "handshake"} {
+		if _, ok := request.Config[key]; ok {
+			t.Errorf("Agent key %q was sent to the provider: %v", key, request.Config)
+		}
+	}
+
+	run("legacy")
+	env, err := os.ReadFile(envFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(env), "GOHL_CONFIG_URL=https://pve.lab") {
+		t.Errorf("Provider setting missing from the environment:\n%s", env)
+	}
+	for _, key := range []string{"PATH", "PUBLIC_KEY", "TIMEOUT"} {
+		if strings.Contains(string(env), "GOHL_CONFIG_"+key+"=") {
+			t.Errorf("Agent key %s was passed in the environment", key)
+		}
+	}
+}
+
+func TestGetProvider_Builtin(t *testing.T) {
+	viper.Reset()
+
+	p, err := GetProvider("system")
+	if err != nil {
+		t.Fatalf("Failed to get builtin provider: %v", err)
+	}
+
+	if p.Info().ID != "system" {
+		t.Errorf("Wrong provider returned: %s", p.Info().ID)
+	}
+}
+
+func TestDescribe_Precedence(t *testing.T) {
+	viper.Reset()
+
+	desc, err := Describe("docker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if desc.Origin != OriginBuiltin {
+		t.Errorf("Expected builtin origin, got %s", desc.Origin)
+	}
+
+	viper.Set("docker.path", "/opt/provider-docker")
+	desc, _ = Describe("docker")
+	if desc.Origin != OriginPath || desc.Location != "/opt/provider-docker" {
+		t.Errorf("Expected path to override builtin, got %+v", desc)
+	}
+
+	viper.Set("docker.source", "github.com/fake/provider-docker")
+	desc, _ = Describe("docker")
+	if desc.Origin != OriginDownload || desc.Version != "latest" {
+		t.Errorf("Expected source to override path, got %+v", desc)
+	}
+}
+
+func TestRegister_Duplicate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic when registering a provider twice")
+		}
+	}()
+
+	Register("system", func() plugin.Scanner { return nil })
+}
+
+func TestSandboxProfile(t *testing.T) {
+	viper.Reset()
+	viper.SetConfigType("yaml")
+	err := viper.ReadConfig(strings.NewReader(`
+downloaded:
+  source: github.com/fake/provider
+local:
+  path: /opt/provider
+opted-out:
+  source: github.com/fake/provider
+  sandbox: false
+strict:
+  path: /opt/provider
+  sandbox:
+    env: [PATH, PROXMOX_*]
+    user: nobody
+    cpu_time: 30s
+    max_memory: 512mb
+    max_output: 1mb
+    namespaces: [pid, ipc]
+    seccomp: true
+disabled:
+  source: github.com/fake/provider
+  sandbox:
+    enabled: false
+broken:
+  path: /opt/provider
+  sandbox: "yes please"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	profile := func(name string, origin Origin) *sandbox.Profile {
+		t.Helper()
+		p, err := sandboxProfile(Descriptor{Name: name, Origin: origin})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		return p
+	}
+
+	if p := profile("downloaded", OriginDownload); p == nil || len(p.Env) != 0 {
+		t.Errorf("downloaded providers should get the default profile, got %+v", p)
+	}
+	if p := profile("local", OriginPath); p != nil {
+		t.Errorf("path providers should not be sandboxed by default, got %+v", p)
+	}
+	if p := profile("opted-out", OriginDownload); p != nil {
+		t.Errorf("'sandbox: false' should disable the sandbox, got %+v", p)
+	}
+	if p := profile("disabled", OriginDownload); p != nil {
+		t.Errorf("'enabled: false' should disable the sandbox, got %+v", p)
+	}
+
+	p := profile("strict", OriginPath)
+	if p == nil {
+		t.Fatal("expected a profile for the sandbox section")
+	}
+	if len(p.Env) != 2 || p.User != "nobody" || p.CPUTime != 30*time.Second || !p.Seccomp {
+		t.Errorf("unexpected profile: %+v", p)
+	}
+	if p.MaxMemory != 512<<20 || p.MaxOutput != 1<<20 || len(p.Namespaces) != 2 {
+		t.Errorf("unexpected limits: %+v", p)
+	}
+
+	if _, err := sandboxProfile(Descriptor{Name: "broken", Origin: OriginPath}); err == nil {
+		t.Error("expected an error for an invalid sandbox value")
+	}
+
+	if _, ok := GetSettings("strict")["sandbox"]; ok {
+		t.Error("sandbox settings must not be passed to the provider")
+	}
+}
@@ -0,0 +1,174 @@
+package registry
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func mockSignedRelease(baseURL string) string {
+	return fmt.Sprintf(`{
+		"tag_name": "v1.0.0",
+		"assets": [
+			{"name": "%s", "browser_download_url": "%s/download/binary"},
+			{"name": "provider-test_checksums.txt", "browser_download_url": "%s/download/checksums.txt"},
+			{"name": "provider-test_checksums.txt.sig", "browser_download_url": "%s/download/checksums.txt.sig"}
+		]
+	}`, testAssetName(), baseURL, baseURL, baseURL)
+}
+
+func runSignedInstall(t *testing.T, spec ProviderSpec, signature []byte, signed bool) error {
+	t.Helper()
+
+	usePluginDir(t)
+
+	var ts *httptest.Server
+	ts = useGitHubServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/download/binary":
+			w.Write([]byte("SIGNED BINARY"))
+		case "/download/checksums.txt":
+			w.Write([]byte(checksumsFor("SIGNED BINARY")))
+		case "/download/checksums.txt.sig":
+			w.Write(signature)
+		default:
+			if signed {
+				w.Write([]byte(mockSignedRelease(ts.URL)))
+			} else {
+				w.Write([]byte(mockGitHubResponse(ts.URL+"/download/binary", "v1.0.0")))
+			}
+		}
+	}))
+
+	_, err := EnsureProvider(spec)
+	return err
+}
+
+func TestEnsureProvider_Signature(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherKey, _ := ed25519.GenerateKey(nil)
+
+	goodSignature := []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, []byte(checksumsFor("SIGNED BINARY")))))
+	badSignature := []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(otherKey, []byte(checksumsFor("SIGNED BINARY")))))
+
+	spec := testSpec
+	spec.PublicKey = base64.StdEncoding.EncodeToString(publicKey)
+	spec.RequireSignature = true
+
+	if err := runSignedInstall(t, spec, goodSignature, true); err != nil {
+		t.Errorf("Valid signature was rejected: %v", err)
+	}
+
+	err = runSignedInstall(t, spec, badSignature, true)
+	if err == nil || !strings.Contains(err.Error(), "signature verification failed") {
+		t.Errorf("Expected signature failure, got %v", err)
+	}
+
+	err = runSignedInstall(t, spec, nil, false)
+	if err == nil || !strings.Contains(err.Error(), "no checksums signature") {
+		t.Errorf("Expected unsigned release to be refused, got %v", err)
+	}
+
+	spec.RequireSignature = false
+	if err := runSignedInstall(t, spec, nil, false); err != nil {
+		t.Errorf("Unsigned release should be allowed without require_signature: %v", err)
+	}
+
+	noKey := testSpec
+	noKey.RequireSignature = true
+	if err := runSignedInstall(t, noKey, goodSignature, true); err == nil {
+		t.Error("Expected error when require_signature is set without public_key")
+	}
+}
+
+func TestParsePublicKey_PEM(t *testing.T) {
+	pemKey := `-----BEGIN PUBLIC KEY-----
+MCowBQYDK2VwAyEAGb9ECWmEzf6FQbrBZ9w7lshQhqowtrbLDFw4rXAxZuE=
+-----END PUBLIC KEY-----`
+
+	key, err := parsePublicKey(pemKey)
+	if err != nil {
+		t.Fatalf("Failed to parse PEM key: %v", err)
+	}
+	if len(key) != ed25519.PublicKeySize {
+		t.Errorf("Wrong key size: %d", len(key))
+	}
+
+	if _, err := parsePublicKey("bm90IGEga2V5"); err == nil {
+		t.Error("Expected error for short key")
+	}
+}
+
+func TestEnsureProvider_SignatureRequiredForCachedBinary(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature := base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, []byte(checksumsFor("SIGNED BINARY"))))
+
+	dir := usePluginDir(t)
+	signed, requests := false, 0
+
+	var ts *httptest.Server
+	ts = useGitHubServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch r.URL.Path {
+		case "/download/binary":
+			w.Write([]byte("SIGNED BINARY"))
+		case "/download/checksums.txt":
+			w.Write([]byte(checksumsFor("SIGNED BINARY")))
+		case "/download/checksums.txt.sig":
+			w.Write([]byte(signature))
+		default:
+			if signed {
+				w.Write([]byte(mockSignedRelease(ts.URL)))
+			} else {
+				w.Write([]byte(mockGitHubResponse(ts.URL+"/download/binary", "v1.0.0")))
+			}
+		}
+	}))
+
+	spec := testSpec
+	spec.Version = "v1.0.0"
+	spec.PublicKey = base64.StdEncoding.EncodeToString(publicKey)
+
+	path, err := EnsureProvider(spec)
+	if err != nil {
+		t.Fatalf("Unsigned install failed: %v", err)
+	}
+	if _, err := os.Stat(path + ".signer"); !os.IsNotExist(err) {
+		t.Error("Unsigned install must not record a signer")
+	}
+
+	// The cached binary was never signature checked, so it is not reused.
+	spec.RequireSignature = true
+	if _, err := EnsureProvider(spec); err == nil || !strings.Contains(err.Error(), "no checksums signature") {
+		t.Fatalf("Expected the unsigned cache to be refused, got %v", err)
+	}
+
+	signed = true
+	if _, err := EnsureProvider(spec); err != nil {
+		t.Fatalf("Signed install failed: %v", err)
+	}
+
+	before := requests
+	if _, err := EnsureProvider(spec); err != nil || requests != before {
+		t.Errorf("Signed cache should be reused offline, got %v after %d requests", err, requests-before)
+	}
+
+	// Neither is a copy dropped into the plugin dir with a matching digest.
+	os.Remove(filepath.Join(dir, filepath.Base(path)+".signer"))
+	signed = false
+	if _, err := EnsureProvider(spec); err == nil {
+		t.Error("Expected a binary without a recorded signer to be refused")
+	}
+}
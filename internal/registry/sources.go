@@ -0,0 +1,507 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/danielvollbro/gohl/internal/compat"
+)
+
+// Source resolves a requested provider version ("latest" or a tag) into a
+// release with downloadable assets. Releases lists what is available, so
+// version ranges can be matched against it.
+type Source interface {
+	Release(version string) (*Release, error)
+	Releases() ([]Release, error)
+}
+
+// Release is a published provider version. Requires is only filled in by
+// sources that carry requirements inline; others ship a manifestName asset.
+// Local marks assets that are files on this machine, which only a file://
+// source may hand out.
+type Release struct {
+	Version  string
+	Assets   []Asset
+	Requires compat.Requirements
+	Local    bool
+}
+
+type Asset struct {
+	Name string
+	URL  string
+}
+
+// GitHubRelease is the release payload of the GitHub API. Gitea and Forgejo
+// return the same shape.
+type GitHubRelease struct {
+	TagName string `json:"tag_name"`
+	Assets  []struct {
+		Name               string `json:"name"`
+		BrowserDownloadURL string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+// NewSource picks the source implementation from the scheme of a 'source:'
+// value:
+//
+//	github.com/owner/repo            GitHub (also github://owner/repo and
+//	                                 https://github.com/owner/repo)
+//	gitea://host/owner/repo          Gitea, forgejo:// is an alias
+//	gitlab://host/group/project      GitLab, nested groups allowed
+//	index+https://host/index.json    static JSON release index
+//	file:///srv/mirror/provider      local directory mirror
+//
+// The gitea, forgejo and gitlab schemes take a '+http' suffix for instances
+// that are served without TLS. Plain URLs must point at github.com; other
+// hosts need their scheme.
+func NewSource(raw string) (Source, error) {
+	scheme, rest, hasScheme := strings.Cut(raw, "://")
+	if !hasScheme {
+		return newGitHubURLSource(raw)
+	}
+
+	kind, transport, explicitTransport := strings.Cut(scheme, "+")
+	if !explicitTransport {
+		transport = "https"
+	}
+	if transport != "http" && transport != "https" {
+		return nil, fmt.Errorf("unsupported transport '%s' in source %s", transport, raw)
+	}
+
+	switch kind {
+	case "http", "https":
+		return newGitHubURLSource(rest)
+	case "github":
+		return newGitHubSource(rest)
+	case "gitea", "forgejo":
+		host, repoPath, err := splitHostPath(rest, 2)
+		if err != nil {
+			return nil, err
+		}
+		return &giteaSource{baseURL: transport + "://" + host, repoPath: repoPath}, nil
+	case "gitlab":
+		host, projectPath, err := splitHostPath(rest, 2)
+		if err != nil {
+			return nil, err
+		}
+		return &gitlabSource{baseURL: transport + "://" + host, project: projectPath}, nil
+	case "index":
+		if !explicitTransport {
+			return nil, fmt.Errorf("index sources must use index+http:// or index+https://")
+		}
+		return &indexSource{url: transport + "://" + rest}, nil
+	case "file":
+		return &dirSource{dir: filepath.FromSlash(rest)}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported source scheme '%s'", scheme)
+}
+
+func splitHostPath(value string, minSegments int) (string, string, error) {
+	host, rest, _ := strings.Cut(strings.Trim(value, "/"), "/")
+	if host == "" || strings.Count(rest, "/")+1 < minSegments || rest == "" {
+		return "", "", fmt.Errorf("invalid source format: %s", value)
+	}
+	return host, strings.TrimSuffix(rest, ".git"), nil
+}
+
+func getJSON(apiURL string, headers map[string]string, out interface{}) error {
+	req, err := http.NewRequest("GET", apiURL, nil)
+	if err != nil {
+		return err
+	}
+	for key, value := range headers {
+		if value != "" {
+			req.Header.Set(key, value)
+		}
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("release api error: %s", resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// maxReleasePages bounds how many pages of a release listing are fetched.
+const maxReleasePages = 10
+
+// getReleasePages follows a paginated release listing, asking for pageSize
+// entries per page until a page comes back short.
+func getReleasePages[T any](apiURL string, pageSize int, headers map[string]string) ([]T, error) {
+	var all []T
+	for page := 1; page <= maxReleasePages; page++ {
+		var payload []T
+		if err := getJSON(fmt.Sprintf("%s&page=%d", apiURL, page), headers, &payload); err != nil {
+			return nil, err
+		}
+		all = append(all, payload...)
+		if len(payload) < pageSize {
+			break
+		}
+	}
+	return all, nil
+}
+
+func tokenHeader(envVar string) string {
+	if token := os.Getenv(envVar); token != "" {
+		return "token " + token
+	}
+	return ""
+}
+
+func isLatest(version string) bool {
+	return version == "" || version == "latest"
+}
+
+type githubSource struct {
+	owner, repo string
+}
+
+func newGitHubSource(raw string) (Source, error) {
+	parts := strings.Split(strings.Trim(raw, "/"), "/")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("invalid source format")
+	}
+	return &githubSource{owner: parts[len(parts)-2], repo: parts[len(parts)-1]}, nil
+}
+
+// newGitHubURLSource accepts "github.com/owner/repo", with the scheme already
+// stripped.
+func newGitHubURLSource(value string) (Source, error) {
+	host, repoPath, err := splitHostPath(value, 2)
+	if err != nil {
+		return nil, err
+	}
+	if host != "github.com" && host != "www.github.com" {
+		return nil, fmt.Errorf("unsupported source host '%s', use gitea://, gitlab:// or index+https:// for servers other than github.com", host)
+	}
+	return newGitHubSource(repoPath)
+}
+
+func (s *githubSource) Release(version string) (*Release, error) {
+	apiURL := fmt.Sprintf("%s/repos/%s/%s/releases/latest", GitHubBaseURL, s.owner, s.repo)
+	if !isLatest(version) {
+		apiURL = fmt.Sprintf("%s/repos/%s/%s/releases/tags/%s", GitHubBaseURL, s.owner, s.repo, version)
+	}
+
+	var release GitHubRelease
+	if err := getJSON(apiURL, map[string]string{"Authorization": tokenHeader("GITHUB_TOKEN")}, &release); err != nil {
+		return nil, err
+	}
+	return release.toRelease(), nil
+}
+
+func (s *githubSource) Releases() ([]Release, error) {
+	apiURL := fmt.Sprintf("%s/repos/%s/%s/releases?per_page=100", GitHubBaseURL, s.owner, s.repo)
+
+	payload, err := getReleasePages[GitHubRelease](apiURL, 100, map[string]string{"Authorization": tokenHeader("GITHUB_TOKEN")})
+	if err != nil {
+		return nil, err
+	}
+
+	releases := make([]Release, 0, len(payload))
+	for _, release := range payload {
+		releases = append(releases, *release.toRelease())
+	}
+	return releases, nil
+}
+
+func (r GitHubRelease) toRelease() *Release {
+	release := &Release{Version: r.TagName}
+	for _, asset := range r.Assets {
+		release.Assets = append(release.Assets, Asset{Name: asset.Name, URL: asset.BrowserDownloadURL})
+	}
+	return release
+}
+
+type giteaSource struct {
+	baseURL, repoPath string
+}
+
+func (s *giteaSource) Release(version string) (*Release, error) {
+	apiURL := fmt.Sprintf("%s/api/v1/repos/%s/releases/latest", s.baseURL, s.repoPath)
+	if !isLatest(version) {
+		apiURL = fmt.Sprintf("%s/api/v1/repos/%s/releases/tags/%s", s.baseURL, s.repoPath, url.PathEscape(version))
+	}
+
+	var release GitHubRelease
+	if err := getJSON(apiURL, map[string]string{"Authorization": tokenHeader("GITEA_TOKEN")}, &release); err != nil {
+		return nil, err
+	}
+	return release.toRelease(), nil
+}
+
+func (s *giteaSource) Releases() ([]Release, error) {
+	apiURL := fmt.Sprintf("%s/api/v1/repos/%s/releases?limit=50", s.baseURL, s.repoPath)
+
+	payload, err := getReleasePages[GitHubRelease](apiURL, 50, map[string]string{"Authorization": tokenHeader("GITEA_TOKEN")})
+	if err != nil {
+		return nil, err
+	}
+
+	releases := make([]Release, 0, len(payload))
+	for _, release := range payload {
+		releases = append(releases, *release.toRelease())
+	}
+	return releases, nil
+}
+
+type gitlabSource struct {
+	baseURL, project string
+}
+
+type gitlabRelease struct {
+	TagName string `json:"tag_name"`
+	Assets  struct {
+		Links []struct {
+			Name           string `json:"name"`
+			URL            string `json:"url"`
+			DirectAssetURL string `json:"direct_asset_url"`
+		} `json:"links"`
+	} `json:"assets"`
+}
+
+func (s *gitlabSource) Release(version string) (*Release, error) {
+	project := url.PathEscape(s.project)
+	apiURL := fmt.Sprintf("%s/api/v4/projects/%s/releases/permalink/latest", s.baseURL, project)
+	if !isLatest(version) {
+		apiURL = fmt.Sprintf("%s/api/v4/projects/%s/releases/%s", s.baseURL, project, url.PathEscape(version))
+	}
+
+	var payload gitlabRelease
+	if err := getJSON(apiURL, map[string]string{"PRIVATE-TOKEN": os.Getenv("GITLAB_TOKEN")}, &payload); err != nil {
+		return nil, err
+	}
+	return payload.toRelease(), nil
+}
+
+func (s *gitlabSource) Releases() ([]Release, error) {
+	apiURL := fmt.Sprintf("%s/api/v4/projects/%s/releases?per_page=100", s.baseURL, url.PathEscape(s.project))
+
+	payload, err := getReleasePages[gitlabRelease](apiURL, 100, map[string]string{"PRIVATE-TOKEN": os.Getenv("GITLAB_TOKEN")})
+	if err != nil {
+		return nil, err
+	}
+
+	releases := make([]Release, 0, len(payload))
+	for _, release := range payload {
+		releases = append(releases, *release.toRelease())
+	}
+	return releases, nil
+}
+
+func (r gitlabRelease) toRelease() *Release {
+	release := &Release{Version: r.TagName}
+	for _, link := range r.Assets.Links {
+		assetURL := link.DirectAssetURL
+		if assetURL == "" {
+			assetURL = link.URL
+		}
+		release.Assets = append(release.Assets, Asset{Name: link.Name, URL: assetURL})
+	}
+	return release
+}
+
+// indexSource reads a static JSON file listing releases, newest first:
+//
+//	{"latest": "v1.1.0", "releases": [{"version": "v1.1.0", "min_agent_version": "0.2.0",
+//	  "api_version": "0.4", "assets": [{"name": "...", "url": "..."}]}]}
+//
+// Relative asset URLs are resolved against the index URL.
+type indexSource struct {
+	url string
+}
+
+type releaseIndex struct {
+	Latest   string `json:"latest"`
+	Releases []struct {
+		Version string  `json:"version"`
+		Assets  []Asset `json:"assets"`
+		compat.Requirements
+	} `json:"releases"`
+}
+
+func (s *indexSource) Release(version string) (*Release, error) {
+	index, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := version
+	if isLatest(version) {
+		wanted = index.Latest
+		if wanted == "" {
+			wanted = index.Releases[0].Version
+		}
+	}
+
+	releases, err := s.releases(index)
+	if err != nil {
+		return nil, err
+	}
+	for i := range releases {
+		if releases[i].Version == wanted {
+			return &releases[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("release %s not found in index %s", wanted, s.url)
+}
+
+func (s *indexSource) Releases() ([]Release, error) {
+	index, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	return s.releases(index)
+}
+
+func (s *indexSource) load() (*releaseIndex, error) {
+	var index releaseIndex
+	if err := getJSON(s.url, nil, &index); err != nil {
+		return nil, err
+	}
+
+	if len(index.Releases) == 0 {
+		return nil, fmt.Errorf("release index %s is empty", s.url)
+	}
+	return &index, nil
+}
+
+func (s *indexSource) releases(index *releaseIndex) ([]Release, error) {
+	base, err := url.Parse(s.url)
+	if err != nil {
+		return nil, err
+	}
+
+	releases := make([]Release, 0, len(index.Releases))
+	for _, entry := range index.Releases {
+		release := Release{Version: entry.Version, Requires: entry.Requirements}
+		for _, asset := range entry.Assets {
+			ref, err := url.Parse(asset.URL)
+			if err != nil {
+				return nil, fmt.Errorf("invalid asset url %s: %v", asset.URL, err)
+			}
+			release.Assets = append(release.Assets, Asset{Name: asset.Name, URL: base.ResolveReference(ref).String()})
+		}
+		releases = append(releases, release)
+	}
+	return releases, nil
+}
+
+// isLocalSource reports whether a configured source is a local mirror.
+func isLocalSource(raw string) bool {
+	source, err := NewSource(raw)
+	if err != nil {
+		return false
+	}
+	_, ok := source.(*dirSource)
+	return ok
+}
+
+// dirSource serves releases from a local mirror with one directory per
+// version. "latest" is read from a 'latest' file in the mirror root, falling
+// back to the highest version directory.
+type dirSource struct {
+	dir string
+}
+
+func (s *dirSource) Release(version string) (*Release, error) {
+	if isLatest(version) {
+		latest, err := s.latestVersion()
+		if err != nil {
+			return nil, err
+		}
+		version = latest
+	}
+
+	if !filepath.IsLocal(version) {
+		return nil, fmt.Errorf("invalid version: %s", version)
+	}
+
+	releaseDir := filepath.Join(s.dir, version)
+	entries, err := os.ReadDir(releaseDir)
+	if err != nil {
+		return nil, fmt.Errorf("release %s not found in mirror: %v", version, err)
+	}
+
+	release := &Release{Version: version, Local: true}
+	for _, entry := range entries {
+		if entry.Type().IsRegular() {
+			assetPath := filepath.ToSlash(filepath.Join(releaseDir, entry.Name()))
+			release.Assets = append(release.Assets, Asset{Name: entry.Name(), URL: "file://" + assetPath})
+		}
+	}
+	return release, nil
+}
+
+func (s *dirSource) Releases() ([]Release, error) {
+	versions, err := s.versions()
+	if err != nil {
+		return nil, err
+	}
+
+	releases := make([]Release, 0, len(versions))
+	for _, version := range versions {
+		release, err := s.Release(version)
+		if err != nil {
+			return nil, err
+		}
+		releases = append(releases, *release)
+	}
+	return releases, nil
+}
+
+func (s *dirSource) versions() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("mirror not readable: %v", err)
+	}
+
+	var versions []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			versions = append(versions, entry.Name())
+		}
+	}
+	return versions, nil
+}
+
+func (s *dirSource) latestVersion() (string, error) {
+	if data, err := os.ReadFile(filepath.Join(s.dir, "latest")); err == nil {
+		return strings.TrimSpace(string(data)), nil
+	}
+
+	versions, err := s.versions()
+	if err != nil {
+		return "", err
+	}
+
+	// Dirs that are not versions, e.g. notes, are ignored.
+	latest, newest := "", compat.Version{}
+	for _, name := range versions {
+		v, err := compat.ParseVersion(name)
+		if err != nil {
+			continue
+		}
+		if latest == "" || v.Compare(newest) > 0 {
+			latest, newest = name, v
+		}
+	}
+	if latest == "" {
+		return "", fmt.Errorf("mirror %s has no releases", s.dir)
+	}
+	return latest, nil
+}
@@ -0,0 +1,325 @@
+package registry
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/danielvollbro/gohl/internal/version"
+)
+
+func TestNewSource_Schemes(t *testing.T) {
+	cases := map[string]string{
+		"github.com/owner/repo":               "*registry.githubSource",
+		"https://github.com/owner/repo":       "*registry.githubSource",
+		"github://owner/repo":                 "*registry.githubSource",
+		"gitea://git.lab/owner/repo":          "*registry.giteaSource",
+		"forgejo+http://git.lab/owner/repo":   "*registry.giteaSource",
+		"gitlab://gitlab.com/group/sub/proj":  "*registry.gitlabSource",
+		"index+https://example.com/idx.json":  "*registry.indexSource",
+		"file:///srv/mirror/provider-example": "*registry.dirSource",
+	}
+
+	for raw, want := range cases {
+		source, err := NewSource(raw)
+		if err != nil {
+			t.Errorf("NewSource(%q) failed: %v", raw, err)
+			continue
+		}
+		if got := fmt.Sprintf("%T", source); got != want {
+			t.Errorf("NewSource(%q) = %s, want %s", raw, got, want)
+		}
+	}
+
+	for _, raw := range []string{"repo", "gitea://git.lab/repo", "index://example.com/idx.json", "svn://host/repo", "gitea+ftp://host/a/b", "gitlab.com/owner/repo", "https://git.lab/owner/repo"} {
+		if _, err := NewSource(raw); err == nil {
+			t.Errorf("Expected NewSource(%q) to fail", raw)
+		}
+	}
+}
+
+func TestGiteaSource(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/repos/homelab/provider-x/releases/latest":
+			w.Write([]byte(`{"tag_name": "v2.0.0", "assets": [{"name": "provider-x_linux_amd64", "browser_download_url": "http://files/x"}]}`))
+		case "/api/v1/repos/homelab/provider-x/releases/tags/v1.0.0":
+			w.Write([]byte(`{"tag_name": "v1.0.0", "assets": []}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	source, err := NewSource("gitea+http://" + strings.TrimPrefix(ts.URL, "http://") + "/homelab/provider-x")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	release, err := source.Release("latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if release.Version != "v2.0.0" || len(release.Assets) != 1 || release.Assets[0].URL != "http://files/x" {
+		t.Errorf("Unexpected release: %+v", release)
+	}
+
+	release, err = source.Release("v1.0.0")
+	if err != nil || release.Version != "v1.0.0" {
+		t.Errorf("Tagged release not resolved: %+v, %v", release, err)
+	}
+}
+
+func TestGitLabSource(t *testing.T) {
+	os.Setenv("GITLAB_TOKEN", "secret")
+	defer os.Unsetenv("GITLAB_TOKEN")
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("PRIVATE-TOKEN") != "secret" {
+			t.Errorf("GitLab token not sent")
+		}
+		if r.URL.EscapedPath() != "/api/v4/projects/infra%2Fgohl%2Fprovider-x/releases/permalink/latest" {
+			t.Errorf("Unexpected path: %s", r.URL.EscapedPath())
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"tag_name": "v1.3.0", "assets": {"links": [
+			{"name": "provider-x_linux_amd64", "url": "http://files/link", "direct_asset_url": "http://files/direct"}
+		]}}`))
+	}))
+	defer ts.Close()
+
+	source, err := NewSource("gitlab+http://" + strings.TrimPrefix(ts.URL, "http://") + "/infra/gohl/provider-x")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	release, err := source.Release("latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if release.Version != "v1.3.0" || release.Assets[0].URL != "http://files/direct" {
+		t.Errorf("Unexpected release: %+v", release)
+	}
+}
+
+func TestIndexSource(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"releases": [
+			{"version": "v1.1.0", "assets": [{"name": "provider-x_linux_amd64", "url": "v1.1.0/provider-x_linux_amd64"}]},
+			{"version": "v1.0.0", "assets": [{"name": "provider-x_linux_amd64", "url": "https://mirror/provider-x"}]}
+		]}`))
+	}))
+	defer ts.Close()
+
+	source, err := NewSource("index+" + ts.URL + "/gohl/provider-x/index.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	release, err := source.Release("latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if release.Version != "v1.1.0" || release.Assets[0].URL != ts.URL+"/gohl/provider-x/v1.1.0/provider-x_linux_amd64" {
+		t.Errorf("Unexpected release: %+v", release)
+	}
+
+	release, err = source.Release("v1.0.0")
+	if err != nil || release.Assets[0].URL != "https://mirror/provider-x" {
+		t.Errorf("Tagged release not resolved: %+v, %v", release, err)
+	}
+
+	if _, err := source.Release("v9.9.9"); err == nil {
+		t.Error("Expected error for unknown version")
+	}
+}
+
+func TestEnsureProvider_LocalMirror(t *testing.T) {
+	mirror, err := os.MkdirTemp("", "gohl-mirror")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(mirror)
+
+	for _, version := range []string{"v1.9.0", "v1.10.0"} {
+		dir := filepath.Join(mirror, version)
+		os.MkdirAll(dir, 0755)
+		content := "MIRROR BINARY " + version
+		os.WriteFile(filepath.Join(dir, testAssetName()), []byte(content), 0755)
+		os.WriteFile(filepath.Join(dir, "checksums.txt"), []byte(checksumsFor(content)), 0644)
+	}
+
+	usePluginDir(t)
+
+	spec := testSpec
+	spec.Source = "file://" + filepath.ToSlash(mirror)
+
+	path, err := EnsureProvider(spec)
+	if err != nil {
+		t.Fatalf("EnsureProvider failed: %v", err)
+	}
+
+	content, _ := os.ReadFile(path)
+	if string(content) != "MIRROR BINARY v1.10.0" {
+		t.Errorf("Expected newest mirrored version, got: %s", string(content))
+	}
+}
+
+func TestEnsureProvider_VersionRange(t *testing.T) {
+	mirror, err := os.MkdirTemp("", "gohl-mirror")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(mirror)
+
+	for _, version := range []string{"v1.1.0", "v1.2.0", "v1.3.0", "v1.4.0", "v2.0.0"} {
+		dir := filepath.Join(mirror, version)
+		os.MkdirAll(dir, 0755)
+		content := "MIRROR BINARY " + version
+		os.WriteFile(filepath.Join(dir, testAssetName()), []byte(content), 0755)
+		os.WriteFile(filepath.Join(dir, "checksums.txt"), []byte(checksumsFor(content)), 0644)
+	}
+	// v1.4.0 needs a newer agent and has to be skipped.
+	os.WriteFile(filepath.Join(mirror, "v1.4.0", manifestName), []byte(`{"min_agent_version": "99.0.0"}`), 0644)
+
+	usePluginDir(t)
+
+	// Development builds ignore min_agent_version, so act like a release.
+	originalAgent := version.Agent
+	version.Agent = "1.0.0"
+	t.Cleanup(func() { version.Agent = originalAgent })
+
+	spec := testSpec
+	spec.Source = "file://" + filepath.ToSlash(mirror)
+	spec.Version = "^1.2"
+
+	path, err := EnsureProvider(spec)
+	if err != nil {
+		t.Fatalf("EnsureProvider failed: %v", err)
+	}
+
+	content, _ := os.ReadFile(path)
+	if string(content) != "MIRROR BINARY v1.3.0" {
+		t.Errorf("Expected newest compatible version in range, got: %s", string(content))
+	}
+
+	spec.Offline = true
+	if cached, err := EnsureProvider(spec); err != nil || cached != path {
+		t.Errorf("Cached binary inside the range not used offline: %s, %v", cached, err)
+	}
+
+	spec.Offline = false
+	spec.Version = "v1.4.0"
+	if _, err := EnsureProvider(spec); err == nil || !strings.Contains(err.Error(), "requires gohl 99.0.0") {
+		t.Errorf("Expected incompatible pinned release to fail, got %v", err)
+	}
+
+	spec.Version = ">=3"
+	if _, err := EnsureProvider(spec); err == nil {
+		t.Error("Expected an error for a range without releases")
+	}
+}
+
+func TestIndexSource_LatestFallsBackToCompatible(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"releases": [
+			{"version": "v2.0.0", "api_version": "9.0", "assets": [{"name": "` + testAssetName() + `", "url": "v2"}]},
+			{"version": "v1.0.0", "assets": [{"name": "` + testAssetName() + `", "url": "v1"}]}
+		]}`))
+	}))
+	defer ts.Close()
+
+	asset, err := resolveRemoteVersion("index+"+ts.URL+"/index.json", "latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if asset.Version != "v1.0.0" {
+		t.Errorf("Expected fallback to the compatible release, got %s", asset.Version)
+	}
+}
+
+func TestDirSource_LatestVersion(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"v1.9.2", "v1.10.0", "v1.10.0-rc.1", "notes"} {
+		os.Mkdir(filepath.Join(dir, name), 0755)
+	}
+
+	latest, err := (&dirSource{dir: dir}).latestVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest != "v1.10.0" {
+		t.Errorf("Expected v1.10.0 to be the newest, got %s", latest)
+	}
+}
+
+func TestReleases_Paginated(t *testing.T) {
+	releasePage := func(w http.ResponseWriter, r *http.Request, size, total int) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		var tags []string
+		for i := (page - 1) * size; i < page*size && i < total; i++ {
+			tags = append(tags, fmt.Sprintf(`{"tag_name": "v0.%d.0"}`, i))
+		}
+		fmt.Fprintf(w, "[%s]", strings.Join(tags, ","))
+	}
+
+	useGitHubServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("per_page") != "100" {
+			t.Errorf("Unexpected GitHub query: %s", r.URL.RawQuery)
+		}
+		releasePage(w, r, 100, 130)
+	}))
+	gitea := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("limit") != "50" {
+			t.Errorf("Unexpected Gitea query: %s", r.URL.RawQuery)
+		}
+		releasePage(w, r, 50, 120)
+	}))
+	defer gitea.Close()
+
+	for raw, want := range map[string]int{
+		"github.com/owner/repo": 130,
+		"gitea+http://" + strings.TrimPrefix(gitea.URL, "http://") + "/owner/repo": 120,
+	} {
+		source, err := NewSource(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		releases, err := source.Releases()
+		if err != nil {
+			t.Fatalf("%s: %v", raw, err)
+		}
+		if len(releases) != want {
+			t.Errorf("%s: expected %d releases over several pages, got %d", raw, want, len(releases))
+		}
+	}
+}
+
+func TestEnsureProvider_RemoteSourceCannotReadLocalFiles(t *testing.T) {
+	usePluginDir(t)
+
+	secret := filepath.Join(t.TempDir(), "secret")
+	os.WriteFile(secret, []byte("LOCAL FILE"), 0644)
+	checksums := filepath.Join(t.TempDir(), "checksums.txt")
+	os.WriteFile(checksums, []byte(checksumsFor("LOCAL FILE")), 0644)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"releases": [{"version": "v1.0.0", "assets": [
+			{"name": "%s", "url": "file://%s"},
+			{"name": "checksums.txt", "url": "file://%s"}
+		]}]}`, testAssetName(), filepath.ToSlash(secret), filepath.ToSlash(checksums))
+	}))
+	defer ts.Close()
+
+	spec := testSpec
+	spec.Source = "index+" + ts.URL + "/index.json"
+	if _, err := EnsureProvider(spec); err == nil || !strings.Contains(err.Error(), "refusing to open") {
+		t.Fatalf("Expected file:// assets from a remote index to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,154 @@
+package sandbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultEnv is the environment allowlist used when a profile does not set
+// its own. Names ending in '*' match by prefix.
+var DefaultEnv = []string{"PATH", "LANG", "LC_*", "TZ", "SSL_CERT_FILE", "SSL_CERT_DIR"}
+
+// Profile restricts how a provider binary is executed. Environment
+// scrubbing, the read-only working directory and the stdout cap apply
+// everywhere. The uid drop, rlimits, namespaces and seccomp are Linux only
+// and make Command fail on other systems, or on kernels that cannot provide
+// them, rather than run the provider with a weaker sandbox.
+//
+// MaxOutput caps what gohl reads from the provider's stdout. It is not an
+// rlimit: RLIMIT_FSIZE only covers files, and the sandbox working directory
+// is read-only anyway.
+type Profile struct {
+	Env        []string      `json:"env,omitempty"`
+	User       string        `json:"user,omitempty"`
+	CPUTime    time.Duration `json:"cpu_time,omitempty"`
+	MaxMemory  uint64        `json:"max_memory,omitempty"`
+	MaxOutput  uint64        `json:"max_output,omitempty"`
+	Namespaces []string      `json:"namespaces,omitempty"`
+	Seccomp    bool          `json:"seccomp,omitempty"`
+}
+
+// limits is what the re-executed helper applies before starting the
+// provider, passed to it in profileEnv.
+type limits struct {
+	CPUTime   time.Duration `json:"cpu_time,omitempty"`
+	MaxMemory uint64        `json:"max_memory,omitempty"`
+	Seccomp   bool          `json:"seccomp,omitempty"`
+}
+
+const (
+	helperArg  = "__gohl-sandbox"
+	profileEnv = "GOHL_SANDBOX"
+)
+
+// Init must run first thing in main. When gohl was re-executed as the
+// sandbox helper it applies the limits and replaces itself with the
+// provider, otherwise it returns immediately.
+func Init() {
+	if len(os.Args) < 3 || os.Args[1] != helperArg {
+		return
+	}
+
+	if err := runHelper(os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "sandbox: %v\n", err)
+		os.Exit(126)
+	}
+}
+
+// Command prepares path to run under the profile. cleanup removes the
+// temporary working directory and must be called once the command has
+// finished. A nil profile runs the command with the agent's environment.
+func (p *Profile) Command(ctx context.Context, path string, args ...string) (*exec.Cmd, func(), error) {
+	if p == nil {
+		cmd := exec.CommandContext(ctx, path, args...)
+		cmd.Env = os.Environ()
+		return cmd, func() {}, nil
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	workDir, err := os.MkdirTemp("", "gohl-sandbox-")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create sandbox dir: %w", err)
+	}
+	cleanup := func() {
+		os.Chmod(workDir, 0700)
+		os.RemoveAll(workDir)
+	}
+	if err := os.Chmod(workDir, 0555); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+
+	useHelper := helperSupported && p.limits() != (limits{})
+
+	var cmd *exec.Cmd
+	if useHelper {
+		self, err := os.Executable()
+		if err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+		cmd = exec.CommandContext(ctx, self, append([]string{helperArg, abs}, args...)...)
+	} else {
+		cmd = exec.CommandContext(ctx, abs, args...)
+	}
+
+	cmd.Dir = workDir
+	cmd.Env = append(p.Environ(os.Environ()), "HOME="+workDir)
+
+	if useHelper {
+		data, err := json.Marshal(p.limits())
+		if err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+		cmd.Env = append(cmd.Env, profileEnv+"="+string(data))
+	}
+
+	if err := p.configure(cmd); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	return cmd, cleanup, nil
+}
+
+// Environ returns the entries of env allowed by the profile.
+func (p *Profile) Environ(env []string) []string {
+	allowed := p.Env
+	if len(allowed) == 0 {
+		allowed = DefaultEnv
+	}
+
+	var out []string
+	for _, entry := range env {
+		name, _, _ := strings.Cut(entry, "=")
+		if name == "HOME" || name == profileEnv {
+			continue
+		}
+		for _, pattern := range allowed {
+			if name == pattern || (strings.HasSuffix(pattern, "*") && strings.HasPrefix(name, strings.TrimSuffix(pattern, "*"))) {
+				out = append(out, entry)
+				break
+			}
+		}
+	}
+	return out
+}
+
+func (p *Profile) limits() limits {
+	return limits{
+		CPUTime:   p.CPUTime,
+		MaxMemory: p.MaxMemory,
+		Seccomp:   p.Seccomp,
+	}
+}
@@ -0,0 +1,138 @@
+//go:build linux
+
+package sandbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+const helperSupported = true
+
+var namespaceFlags = map[string]uintptr{
+	"user":  syscall.CLONE_NEWUSER,
+	"pid":   syscall.CLONE_NEWPID,
+	"net":   syscall.CLONE_NEWNET,
+	"ipc":   syscall.CLONE_NEWIPC,
+	"uts":   syscall.CLONE_NEWUTS,
+	"mount": syscall.CLONE_NEWNS,
+}
+
+func (p *Profile) configure(cmd *exec.Cmd) error {
+	attr := &syscall.SysProcAttr{}
+	cmd.SysProcAttr = attr
+
+	if p.User != "" {
+		if os.Geteuid() != 0 {
+			return fmt.Errorf("dropping to user %q requires running gohl as root", p.User)
+		}
+		uid, gid, err := lookupUser(p.User)
+		if err != nil {
+			return err
+		}
+		attr.Credential = &syscall.Credential{Uid: uid, Gid: gid, Groups: []uint32{}}
+	}
+
+	for _, name := range p.Namespaces {
+		flag, ok := namespaceFlags[name]
+		if !ok {
+			return fmt.Errorf("unknown namespace %q", name)
+		}
+		attr.Cloneflags |= flag
+	}
+
+	// Without root, the other namespaces can only be created inside a user
+	// namespace. A profile is never run with fewer namespaces than it asks
+	// for, so kernels that forbid those fail the command.
+	if attr.Cloneflags != 0 && os.Geteuid() != 0 {
+		if !userNamespacesAllowed() {
+			return fmt.Errorf("namespaces %s need unprivileged user namespaces, which this kernel does not allow; run gohl as root or drop them from the profile", strings.Join(p.Namespaces, ", "))
+		}
+		attr.Cloneflags |= syscall.CLONE_NEWUSER
+		attr.UidMappings = []syscall.SysProcIDMap{{ContainerID: os.Getuid(), HostID: os.Getuid(), Size: 1}}
+		attr.GidMappings = []syscall.SysProcIDMap{{ContainerID: os.Getgid(), HostID: os.Getgid(), Size: 1}}
+	}
+	return nil
+}
+
+func lookupUser(name string) (uint32, uint32, error) {
+	if uid, err := strconv.ParseUint(name, 10, 32); err == nil {
+		return uint32(uid), uint32(uid), nil
+	}
+
+	u, err := user.Lookup(name)
+	if err != nil {
+		return 0, 0, fmt.Errorf("sandbox user: %w", err)
+	}
+	uid, err := strconv.ParseUint(u.Uid, 10, 32)
+	if err != nil {
+		return 0, 0, err
+	}
+	gid, err := strconv.ParseUint(u.Gid, 10, 32)
+	if err != nil {
+		return 0, 0, err
+	}
+	return uint32(uid), uint32(gid), nil
+}
+
+func userNamespacesAllowed() bool {
+	for _, path := range []string{"/proc/sys/kernel/unprivileged_userns_clone", "/proc/sys/user/max_user_namespaces"} {
+		if data, err := os.ReadFile(path); err == nil && strings.TrimSpace(string(data)) == "0" {
+			return false
+		}
+	}
+	return true
+}
+
+// runHelper applies the limits from profileEnv to this process and then
+// execs the provider, which inherits them.
+func runHelper(args []string) error {
+	var l limits
+	if err := json.Unmarshal([]byte(os.Getenv(profileEnv)), &l); err != nil {
+		return fmt.Errorf("invalid profile: %w", err)
+	}
+
+	env := make([]string, 0, len(os.Environ()))
+	for _, entry := range os.Environ() {
+		if !strings.HasPrefix(entry, profileEnv+"=") {
+			env = append(env, entry)
+		}
+	}
+
+	if l.CPUTime > 0 {
+		seconds := uint64((l.CPUTime + 999_999_999) / 1_000_000_000)
+		if err := setLimit(unix.RLIMIT_CPU, seconds); err != nil {
+			return fmt.Errorf("cpu limit: %w", err)
+		}
+	}
+	if l.MaxMemory > 0 {
+		if err := setLimit(unix.RLIMIT_AS, l.MaxMemory); err != nil {
+			return fmt.Errorf("memory limit: %w", err)
+		}
+	}
+
+	// Seccomp filters and no_new_privs are per thread and survive exec, so
+	// both have to be set on the thread that execs the provider. A provider
+	// that asked for seccomp never runs without it.
+	runtime.LockOSThread()
+	if l.Seccomp {
+		if err := installSeccomp(); err != nil {
+			return fmt.Errorf("seccomp: %w", err)
+		}
+	}
+
+	return unix.Exec(args[0], args, env)
+}
+
+func setLimit(resource int, value uint64) error {
+	return unix.Setrlimit(resource, &unix.Rlimit{Cur: value, Max: value})
+}
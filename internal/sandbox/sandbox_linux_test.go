@@ -0,0 +1,32 @@
+package sandbox
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCommand_Namespaces(t *testing.T) {
+	if os.Geteuid() != 0 && !userNamespacesAllowed() {
+		_, _, err := (&Profile{Namespaces: []string{"pid"}}).Command(context.Background(), "/bin/sh")
+		if err == nil || !strings.Contains(err.Error(), "pid") {
+			t.Errorf("expected dropped namespaces to be an error naming them, got %v", err)
+		}
+		return
+	}
+
+	cmd, cleanup, err := (&Profile{Namespaces: []string{"pid"}}).Command(context.Background(), "/bin/sh", "-c", "echo $$")
+	if err != nil {
+		t.Fatalf("Command failed: %v", err)
+	}
+	defer cleanup()
+
+	output, err := cmd.Output()
+	if err != nil {
+		t.Skipf("namespaces not permitted here: %v", err)
+	}
+	if pid := strings.TrimSpace(string(output)); pid != "1" {
+		t.Errorf("expected to be pid 1 in a new pid namespace, got %s", pid)
+	}
+}
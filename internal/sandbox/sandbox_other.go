@@ -0,0 +1,28 @@
+//go:build !linux
+
+package sandbox
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+)
+
+const helperSupported = false
+
+func (p *Profile) configure(cmd *exec.Cmd) error {
+	if p.User != "" || p.CPUTime > 0 || p.MaxMemory > 0 {
+		return fmt.Errorf("sandbox user and resource limits are not supported on %s", runtime.GOOS)
+	}
+	if p.Seccomp {
+		return fmt.Errorf("seccomp is not supported on %s", runtime.GOOS)
+	}
+	if len(p.Namespaces) > 0 {
+		return fmt.Errorf("namespaces are not supported on %s", runtime.GOOS)
+	}
+	return nil
+}
+
+func runHelper(args []string) error {
+	return fmt.Errorf("not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,147 @@
+package sandbox
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The test binary stands in for gohl when a profile needs the helper.
+func TestMain(m *testing.M) {
+	Init()
+	os.Exit(m.Run())
+}
+
+func run(t *testing.T, p *Profile, script string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("sandbox tests need a POSIX shell")
+	}
+
+	cmd, cleanup, err := p.Command(context.Background(), "/bin/sh", "-c", script)
+	if err != nil {
+		t.Fatalf("Command failed: %v", err)
+	}
+	defer cleanup()
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("sandboxed command failed: %v\n%s", err, output)
+	}
+	return string(output)
+}
+
+func TestEnviron(t *testing.T) {
+	env := []string{"PATH=/bin", "GITHUB_TOKEN=secret", "HOME=/root", "LC_ALL=C", "PROXMOX_TOKEN=x", "GOHL_SANDBOX={}"}
+
+	got := strings.Join((&Profile{}).Environ(env), " ")
+	if got != "PATH=/bin LC_ALL=C" {
+		t.Errorf("default allowlist: got %q", got)
+	}
+
+	got = strings.Join((&Profile{Env: []string{"PROXMOX_*", "HOME"}}).Environ(env), " ")
+	if got != "PROXMOX_TOKEN=x" {
+		t.Errorf("custom allowlist: got %q", got)
+	}
+}
+
+func TestCommand_ScrubsEnvironment(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "secret")
+
+	output := run(t, &Profile{}, `echo "token=$GITHUB_TOKEN"; echo "home=$HOME"; echo "pwd=$(pwd)"`)
+
+	if strings.Contains(output, "secret") {
+		t.Errorf("GITHUB_TOKEN leaked into the sandbox:\n%s", output)
+	}
+	if !strings.Contains(output, "pwd=/") || !strings.Contains(output, "gohl-sandbox-") {
+		t.Errorf("expected a private working dir:\n%s", output)
+	}
+}
+
+func TestCommand_ReadOnlyWorkDir(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can write to read-only directories")
+	}
+
+	output := run(t, &Profile{}, `touch file 2>/dev/null && echo writable || echo readonly`)
+	if strings.TrimSpace(output) != "readonly" {
+		t.Errorf("working dir is writable")
+	}
+}
+
+func TestCommand_NilProfile(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "secret")
+
+	output := run(t, nil, `echo "$GITHUB_TOKEN"`)
+	if strings.TrimSpace(output) != "secret" {
+		t.Errorf("nil profile should keep the environment, got %q", output)
+	}
+}
+
+func TestCommand_Limits(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("resource limits are applied on linux only")
+	}
+
+	p := &Profile{CPUTime: 1500 * time.Millisecond, MaxMemory: 256 << 20}
+	output := run(t, p, `ulimit -t; ulimit -v; echo "sandbox=$GOHL_SANDBOX"`)
+
+	lines := strings.Fields(output)
+	if len(lines) != 3 {
+		t.Fatalf("unexpected output:\n%s", output)
+	}
+	if lines[0] != "2" {
+		t.Errorf("cpu limit: got %s, want 2", lines[0])
+	}
+	if lines[1] != "262144" {
+		t.Errorf("memory limit: got %s KiB, want 262144", lines[1])
+	}
+	if lines[2] != "sandbox=" {
+		t.Errorf("helper profile leaked to the provider: %s", lines[2])
+	}
+}
+
+func TestCommand_Seccomp(t *testing.T) {
+	if runtime.GOOS != "linux" || (runtime.GOARCH != "amd64" && runtime.GOARCH != "arm64") {
+		t.Skip("seccomp filter is only built for linux/amd64 and linux/arm64")
+	}
+
+	output := run(t, &Profile{Seccomp: true}, `grep -E '^(Seccomp|NoNewPrivs):' /proc/self/status`)
+	if !strings.Contains(output, "NoNewPrivs:\t1") || !strings.Contains(output, "Seccomp:\t2") {
+		t.Errorf("seccomp filter not active:\n%s", output)
+	}
+}
+
+func TestCommand_UserRequiresRoot(t *testing.T) {
+	if os.Geteuid() == 0 || runtime.GOOS == "windows" {
+		t.Skip("needs an unprivileged user")
+	}
+
+	if _, _, err := (&Profile{User: "nobody"}).Command(context.Background(), "/bin/true"); err == nil {
+		t.Error("expected dropping privileges to fail without root")
+	}
+}
+
+func TestCommand_DropsPrivileges(t *testing.T) {
+	if os.Geteuid() != 0 || runtime.GOOS != "linux" {
+		t.Skip("needs root on linux")
+	}
+
+	output := run(t, &Profile{User: "65534"}, `id -u; touch file 2>/dev/null && echo writable || echo readonly`)
+	if fields := strings.Fields(output); len(fields) != 2 || fields[0] != "65534" || fields[1] != "readonly" {
+		t.Errorf("expected to run as 65534 in a read-only dir, got:\n%s", output)
+	}
+}
+
+func TestCommand_UnknownNamespace(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("namespaces are linux only")
+	}
+
+	if _, _, err := (&Profile{Namespaces: []string{"time-travel"}}).Command(context.Background(), "/bin/true"); err == nil {
+		t.Error("expected an error for an unknown namespace")
+	}
+}
@@ -0,0 +1,10 @@
+//go:build linux && amd64
+
+package sandbox
+
+import "golang.org/x/sys/unix"
+
+const auditArch = unix.AUDIT_ARCH_X86_64
+
+// x32SyscallBit marks x32 ABI syscall numbers.
+const x32SyscallBit = 0x40000000
@@ -0,0 +1,10 @@
+//go:build linux && arm64
+
+package sandbox
+
+import "golang.org/x/sys/unix"
+
+const auditArch = unix.AUDIT_ARCH_AARCH64
+
+// x32SyscallBit is zero because arm64 has no second ABI sharing its arch.
+const x32SyscallBit = 0
@@ -0,0 +1,82 @@
+//go:build linux && (amd64 || arm64)
+
+package sandbox
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+// runFilter evaluates the classic BPF instructions seccompFilter uses
+// against a struct seccomp_data.
+func runFilter(t *testing.T, filter []unix.SockFilter, nr, arch uint32, arg0 uint64) uint32 {
+	t.Helper()
+
+	data := make([]byte, 64)
+	binary.LittleEndian.PutUint32(data[offsetNr:], nr)
+	binary.LittleEndian.PutUint32(data[offsetArch:], arch)
+	binary.LittleEndian.PutUint64(data[offsetArg0:], arg0)
+
+	var acc uint32
+	for pc := 0; pc < len(filter); pc++ {
+		ins := filter[pc]
+		jump := func(cond bool) {
+			if cond {
+				pc += int(ins.Jt)
+			} else {
+				pc += int(ins.Jf)
+			}
+		}
+
+		switch ins.Code {
+		case unix.BPF_LD | unix.BPF_W | unix.BPF_ABS:
+			acc = binary.LittleEndian.Uint32(data[ins.K:])
+		case unix.BPF_JMP | unix.BPF_JEQ | unix.BPF_K:
+			jump(acc == ins.K)
+		case unix.BPF_JMP | unix.BPF_JGE | unix.BPF_K:
+			jump(acc >= ins.K)
+		case unix.BPF_JMP | unix.BPF_JSET | unix.BPF_K:
+			jump(acc&ins.K != 0)
+		case unix.BPF_RET | unix.BPF_K:
+			return ins.K
+		default:
+			t.Fatalf("unexpected instruction %#x at %d", ins.Code, pc)
+		}
+	}
+	t.Fatal("filter fell off the end")
+	return 0
+}
+
+func TestSeccompFilter(t *testing.T) {
+	filter := seccompFilter()
+	eperm := uint32(unix.SECCOMP_RET_ERRNO | uint32(unix.EPERM))
+
+	type filterCase struct {
+		name string
+		nr   uint32
+		arch uint32
+		arg0 uint64
+		want uint32
+	}
+	cases := []filterCase{
+		{"getpid", unix.SYS_GETPID, auditArch, 0, unix.SECCOMP_RET_ALLOW},
+		{"ptrace", unix.SYS_PTRACE, auditArch, 0, eperm},
+		{"bpf", unix.SYS_BPF, auditArch, 0, eperm},
+		{"foreign arch", unix.SYS_GETPID, unix.AUDIT_ARCH_I386, 0, unix.SECCOMP_RET_KILL_PROCESS},
+		{"thread clone", unix.SYS_CLONE, auditArch, unix.CLONE_VM | unix.CLONE_THREAD | unix.CLONE_SIGHAND, unix.SECCOMP_RET_ALLOW},
+		{"clone new user ns", unix.SYS_CLONE, auditArch, unix.CLONE_NEWUSER, eperm},
+		{"clone new pid ns", unix.SYS_CLONE, auditArch, unix.CLONE_NEWPID | unix.CLONE_NEWNS, eperm},
+		{"clone3", unix.SYS_CLONE3, auditArch, 0, unix.SECCOMP_RET_ERRNO | uint32(unix.ENOSYS)},
+	}
+	if x32SyscallBit != 0 {
+		cases = append(cases, filterCase{"x32 ptrace", x32SyscallBit | unix.SYS_PTRACE, auditArch, 0, unix.SECCOMP_RET_KILL_PROCESS})
+	}
+
+	for _, c := range cases {
+		if got := runFilter(t, filter, c.nr, c.arch, c.arg0); got != c.want {
+			t.Errorf("%s: got action %#x, want %#x", c.name, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,12 @@
+//go:build linux && !amd64 && !arm64
+
+package sandbox
+
+import (
+	"fmt"
+	"runtime"
+)
+
+func installSeccomp() error {
+	return fmt.Errorf("no seccomp filter for %s", runtime.GOARCH)
+}
@@ -0,0 +1,177 @@
+package scan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/danielvollbro/gohl/pkg/plugin"
+
+	api "github.com/danielvollbro/gohl-api"
+)
+
+const (
+	DefaultWorkers = 4
+	DefaultTimeout = 2 * time.Minute
+)
+
+type State int
+
+const (
+	StatePending State = iota
+	StateRunning
+	StateDone
+	StateFailed
+)
+
+type Job struct {
+	Name    string
+	Scanner plugin.Scanner
+	Config  map[string]string
+	Timeout time.Duration
+}
+
+// Event reports a job changing state. While a job is running, scanners that
+// stream their results send further StateRunning events with Progress set.
+type Event struct {
+	Name     string
+	State    State
+	Err      error
+	Progress *plugin.Progress
+}
+
+// Result holds the outcome of one job. A failed job may still carry the
+// checks its scanner finished before failing, in which case Partial is set.
+type Result struct {
+	Name     string
+	Report   *api.ScanReport
+	Err      error
+	Partial  bool
+	Duration time.Duration
+}
+
+// Run executes jobs on at most workers goroutines and returns the results in
+// job order. onEvent is called from the worker goroutines whenever a job
+// changes state and may be nil.
+func Run(ctx context.Context, jobs []Job, workers int, onEvent func(Event)) []Result {
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	if onEvent == nil {
+		onEvent = func(Event) {}
+	}
+
+	results := make([]Result, len(jobs))
+	queue := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < len(jobs); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				results[i] = runJob(ctx, jobs[i], onEvent)
+			}
+		}()
+	}
+
+	for i := range jobs {
+		onEvent(Event{Name: jobs[i].Name, State: StatePending})
+	}
+	for i := range jobs {
+		queue <- i
+	}
+	close(queue)
+	wg.Wait()
+
+	return results
+}
+
+func runJob(ctx context.Context, job Job, onEvent func(Event)) Result {
+	timeout := job.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	onEvent(Event{Name: job.Name, State: StateRunning})
+	started := time.Now()
+
+	var (
+		streamedMu sync.Mutex
+		streamed   []api.CheckResult
+		finished   bool
+	)
+	ctx = plugin.WithProgress(ctx, func(p plugin.Progress) {
+		streamedMu.Lock()
+		defer streamedMu.Unlock()
+		if finished {
+			return
+		}
+		if p.Check != nil {
+			streamed = append(streamed, *p.Check)
+		}
+		onEvent(Event{Name: job.Name, State: StateRunning, Progress: &p})
+	})
+
+	type outcome struct {
+		report *api.ScanReport
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		report, err := job.Scanner.Analyze(ctx, job.Config)
+		done <- outcome{report, err}
+	}()
+
+	// Scanners are expected to honor ctx, but one that does not must not be
+	// able to hold up the whole scan.
+	var result Result
+	select {
+	case out := <-done:
+		result = Result{Name: job.Name, Report: out.report, Err: out.err}
+	case <-ctx.Done():
+		result = Result{Name: job.Name, Err: ctx.Err()}
+
+		// Fall back to what was streamed if the scanner does not return.
+		streamedMu.Lock()
+		if len(streamed) > 0 {
+			result.Report = &api.ScanReport{
+				PluginID: job.Scanner.Info().ID,
+				Checks:   append([]api.CheckResult(nil), streamed...),
+			}
+		}
+		streamedMu.Unlock()
+	}
+	result.Duration = time.Since(started)
+
+	streamedMu.Lock()
+	finished = true
+	streamedMu.Unlock()
+
+	if result.Err == nil && result.Report == nil {
+		result.Err = fmt.Errorf("provider returned no report")
+	}
+	if result.Err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		result.Err = fmt.Errorf("timed out after %s", timeout)
+	}
+
+	if result.Err != nil && result.Report != nil {
+		if len(result.Report.Checks) > 0 {
+			result.Partial = true
+		} else {
+			result.Report = nil
+		}
+	}
+
+	if result.Err != nil {
+		onEvent(Event{Name: job.Name, State: StateFailed, Err: result.Err})
+	} else {
+		onEvent(Event{Name: job.Name, State: StateDone})
+	}
+	return result
+}
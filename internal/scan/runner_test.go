@@ -0,0 +1,167 @@
+package scan
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/danielvollbro/gohl/pkg/plugin"
+
+	api "github.com/danielvollbro/gohl-api"
+)
+
+type fakeScanner struct {
+	id      string
+	delay   time.Duration
+	err     error
+	ignores bool
+	active  *int32
+	peak    *int32
+}
+
+func (f *fakeScanner) Info() api.PluginInfo {
+	return api.PluginInfo{ID: f.id, Name: f.id}
+}
+
+func (f *fakeScanner) Analyze(ctx context.Context, config map[string]string) (*api.ScanReport, error) {
+	if f.active != nil {
+		now := atomic.AddInt32(f.active, 1)
+		defer atomic.AddInt32(f.active, -1)
+		for {
+			peak := atomic.LoadInt32(f.peak)
+			if now <= peak || atomic.CompareAndSwapInt32(f.peak, peak, now) {
+				break
+			}
+		}
+	}
+
+	if f.ignores {
+		time.Sleep(f.delay)
+	} else {
+		select {
+		case <-time.After(f.delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &api.ScanReport{PluginID: f.id}, nil
+}
+
+func TestRun_OrderAndErrors(t *testing.T) {
+	jobs := []Job{
+		{Name: "slow", Scanner: &fakeScanner{id: "slow", delay: 30 * time.Millisecond}},
+		{Name: "broken", Scanner: &fakeScanner{id: "broken", err: errors.New("boom")}},
+		{Name: "fast", Scanner: &fakeScanner{id: "fast"}},
+	}
+
+	var mu sync.Mutex
+	states := make(map[string][]State)
+	results := Run(context.Background(), jobs, 3, func(e Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		states[e.Name] = append(states[e.Name], e.State)
+	})
+
+	if len(results) != 3 || results[0].Name != "slow" || results[2].Name != "fast" {
+		t.Fatalf("Results not in job order: %+v", results)
+	}
+	if results[0].Report == nil || results[0].Report.PluginID != "slow" {
+		t.Errorf("Missing report for slow provider")
+	}
+	if results[1].Err == nil || results[1].Err.Error() != "boom" {
+		t.Errorf("Expected error from broken provider, got %v", results[1].Err)
+	}
+
+	final := states["broken"][len(states["broken"])-1]
+	if final != StateFailed {
+		t.Errorf("Expected broken provider to end failed, got %v", final)
+	}
+	if states["fast"][len(states["fast"])-1] != StateDone {
+		t.Errorf("Expected fast provider to end done, got %v", states["fast"])
+	}
+}
+
+func TestRun_Timeout(t *testing.T) {
+	jobs := []Job{
+		{Name: "hangs", Scanner: &fakeScanner{id: "hangs", delay: time.Second}, Timeout: 20 * time.Millisecond},
+		{Name: "stubborn", Scanner: &fakeScanner{id: "stubborn", delay: time.Second, ignores: true}, Timeout: 20 * time.Millisecond},
+	}
+
+	started := time.Now()
+	results := Run(context.Background(), jobs, 2, nil)
+
+	if elapsed := time.Since(started); elapsed > 500*time.Millisecond {
+		t.Errorf("Timeouts were not enforced, scan took %s", elapsed)
+	}
+
+	for _, result := range results {
+		if result.Err == nil || !strings.Contains(result.Err.Error(), "timed out") {
+			t.Errorf("Expected timeout for %s, got %v", result.Name, result.Err)
+		}
+	}
+}
+
+func TestRun_WorkerLimit(t *testing.T) {
+	var active, peak int32
+	var jobs []Job
+	for i := 0; i < 6; i++ {
+		jobs = append(jobs, Job{Name: "job", Scanner: &fakeScanner{id: "job", delay: 10 * time.Millisecond, active: &active, peak: &peak}})
+	}
+
+	Run(context.Background(), jobs, 2, nil)
+
+	if peak > 2 {
+		t.Errorf("Expected at most 2 concurrent providers, saw %d", peak)
+	}
+}
+
+type streamingScanner struct{}
+
+func (s *streamingScanner) Info() api.PluginInfo {
+	return api.PluginInfo{ID: "streaming"}
+}
+
+// Analyze streams two checks and then hangs, ignoring ctx.
+func (s *streamingScanner) Analyze(ctx context.Context, config map[string]string) (*api.ScanReport, error) {
+	for _, id := range []string{"a", "b"} {
+		plugin.ReportProgress(ctx, plugin.Progress{Check: &api.CheckResult{ID: id, Passed: true}})
+	}
+	time.Sleep(time.Second)
+	return nil, errors.New("too late")
+}
+
+func TestRun_StreamedChecksSurviveTimeout(t *testing.T) {
+	var mu sync.Mutex
+	var progress int
+
+	jobs := []Job{{Name: "streaming", Scanner: &streamingScanner{}, Timeout: 50 * time.Millisecond}}
+	results := Run(context.Background(), jobs, 1, func(e Event) {
+		if e.Progress != nil {
+			mu.Lock()
+			progress++
+			mu.Unlock()
+		}
+	})
+
+	result := results[0]
+	if result.Err == nil || !result.Partial {
+		t.Fatalf("Expected a partial failure, got err=%v partial=%v", result.Err, result.Partial)
+	}
+	if result.Report == nil || len(result.Report.Checks) != 2 || result.Report.PluginID != "streaming" {
+		t.Errorf("Streamed checks were not kept: %+v", result.Report)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if progress != 2 {
+		t.Errorf("Expected 2 progress events, got %d", progress)
+	}
+}
@@ -9,10 +9,24 @@ import (
 	"strings"
 	"time"
 
-	api "github.com/danielvollbro/gohl-api"
+	"github.com/danielvollbro/gohl/internal/game"
 )
 
-const historyDirName = ".gohl/history"
+const (
+	stateDirName   = ".gohl"
+	historyDirName = ".gohl/history"
+
+	achievementsFile = "achievements.json"
+	questsFile       = "quests.json"
+	xpFile           = "xp.json"
+
+	// HistoryDays is how far back LoadHistory reads by default. Streaks
+	// and quests only look at recent scans, so older reports are left on
+	// disk unread.
+	HistoryDays = 30
+
+	historyTimeFormat = "2006-01-02T15-04-05"
+)
 
 func getHistoryDir() (string, error) {
 	home, err := os.UserHomeDir()
@@ -29,13 +43,13 @@ func getHistoryDir() (string, error) {
 	return path, nil
 }
 
-func Save(report api.GrandReport) error {
+func Save(report game.Report) error {
 	dir, err := getHistoryDir()
 	if err != nil {
 		return err
 	}
 
-	filename := fmt.Sprintf("report_%s.json", time.Now().Format("2006-01-02T15-04-05"))
+	filename := fmt.Sprintf("report_%s.json", time.Now().Format(historyTimeFormat))
 	path := filepath.Join(dir, filename)
 
 	data, err := json.MarshalIndent(report, "", "  ")
@@ -46,7 +60,30 @@ func Save(report api.GrandReport) error {
 	return os.WriteFile(path, data, 0644)
 }
 
-func LoadLatest() (*api.GrandReport, error) {
+// LoadHistory returns the reports saved since the given time, oldest first.
+// Files that cannot be read are skipped.
+func LoadHistory(since time.Time) ([]game.Report, error) {
+	files, err := historyFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	// File names sort by the local time they were saved at.
+	first := fmt.Sprintf("report_%s.json", since.Local().Format(historyTimeFormat))
+
+	var reports []game.Report
+	for _, file := range files {
+		if filepath.Base(file) < first {
+			continue
+		}
+		if report, err := loadReport(file); err == nil {
+			reports = append(reports, *report)
+		}
+	}
+	return reports, nil
+}
+
+func historyFiles() ([]string, error) {
 	dir, err := getHistoryDir()
 	if err != nil {
 		return nil, err
@@ -64,22 +101,101 @@ func LoadLatest() (*api.GrandReport, error) {
 		}
 	}
 
-	if len(jsonFiles) == 0 {
-		return nil, nil
-	}
-
 	sort.Strings(jsonFiles)
-	latestFile := jsonFiles[len(jsonFiles)-1]
+	return jsonFiles, nil
+}
 
-	data, err := os.ReadFile(latestFile)
+func loadReport(path string) (*game.Report, error) {
+	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
 
-	var report api.GrandReport
+	var report game.Report
 	if err := json.Unmarshal(data, &report); err != nil {
 		return nil, err
 	}
 
 	return &report, nil
 }
+
+func getStateFile(name string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(home, stateDirName)
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// loadState decodes a state file next to the history, leaving out untouched
+// when the file does not exist yet.
+func loadState(name string, out interface{}) error {
+	path, err := getStateFile(name)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+func saveState(name string, value interface{}) error {
+	path, err := getStateFile(name)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func LoadAchievements() ([]game.Achievement, error) {
+	var achievements []game.Achievement
+	return achievements, loadState(achievementsFile, &achievements)
+}
+
+func SaveAchievements(achievements []game.Achievement) error {
+	stored := make([]game.Achievement, len(achievements))
+	for i, achievement := range achievements {
+		achievement.New = false
+		stored[i] = achievement
+	}
+	return saveState(achievementsFile, stored)
+}
+
+func LoadQuests() ([]game.Quest, error) {
+	var quests []game.Quest
+	return quests, loadState(questsFile, &quests)
+}
+
+func SaveQuests(quests []game.Quest) error {
+	return saveState(questsFile, quests)
+}
+
+func LoadLedger() (game.Ledger, error) {
+	var ledger game.Ledger
+	return ledger, loadState(xpFile, &ledger)
+}
+
+func SaveLedger(ledger game.Ledger) error {
+	return saveState(xpFile, ledger)
+}
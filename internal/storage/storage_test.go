@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/danielvollbro/gohl/internal/game"
+)
+
+func useHome(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	return home
+}
+
+func TestLoadState_Missing(t *testing.T) {
+	useHome(t)
+
+	ledger := game.Ledger{Total: 42}
+	if err := loadState(xpFile, &ledger); err != nil {
+		t.Fatalf("loadState failed: %v", err)
+	}
+	if ledger.Total != 42 {
+		t.Errorf("missing state file should leave the value untouched, got %+v", ledger)
+	}
+}
+
+func TestSaveState_RoundTrip(t *testing.T) {
+	home := useHome(t)
+
+	quests := []game.Quest{{ID: "system/sys-swap", Impact: 12}}
+	if err := saveState(questsFile, quests); err != nil {
+		t.Fatalf("saveState failed: %v", err)
+	}
+
+	var loaded []game.Quest
+	if err := loadState(questsFile, &loaded); err != nil {
+		t.Fatalf("loadState failed: %v", err)
+	}
+	if len(loaded) != 1 || loaded[0].ID != "system/sys-swap" || loaded[0].Impact != 12 {
+		t.Errorf("unexpected quests after a round trip: %+v", loaded)
+	}
+
+	if _, err := os.Stat(filepath.Join(home, stateDirName, questsFile+".tmp")); !os.IsNotExist(err) {
+		t.Error("temporary state file was left behind")
+	}
+}
+
+func TestLoadState_Corrupt(t *testing.T) {
+	home := useHome(t)
+
+	os.MkdirAll(filepath.Join(home, stateDirName), 0755)
+	os.WriteFile(filepath.Join(home, stateDirName, xpFile), []byte("{not json"), 0644)
+
+	var ledger game.Ledger
+	if err := loadState(xpFile, &ledger); err == nil {
+		t.Error("expected an error for a corrupt state file")
+	}
+}
+
+func TestLoadHistory_Window(t *testing.T) {
+	home := useHome(t)
+
+	dir := filepath.Join(home, historyDirName)
+	os.MkdirAll(dir, 0755)
+
+	now := time.Now()
+	write := func(at time.Time, content string) {
+		name := "report_" + at.Format(historyTimeFormat) + ".json"
+		os.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+	}
+	write(now.AddDate(0, 0, -40), `{"lab_id": "old", "score": 10}`)
+	write(now.AddDate(0, 0, -2), `{"lab_id": "recent", "score": 20}`)
+	write(now.AddDate(0, 0, -1), `{broken`)
+	write(now.Add(-time.Hour), `{"lab_id": "latest", "score": 30}`)
+
+	reports, err := LoadHistory(now.AddDate(0, 0, -HistoryDays))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 2 || reports[0].Score != 20 || reports[1].Score != 30 {
+		t.Errorf("expected the two readable reports inside the window, got %+v", reports)
+	}
+}
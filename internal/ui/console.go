@@ -3,13 +3,50 @@ package ui
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/danielvollbro/gohl/internal/game"
+	"github.com/danielvollbro/gohl/internal/version"
 	"github.com/pterm/pterm"
 
 	api "github.com/danielvollbro/gohl-api"
 )
 
+// rankColors are the color names rank ladders can use.
+var rankColors = map[string]pterm.Color{
+	"black":         pterm.FgBlack,
+	"red":           pterm.FgRed,
+	"green":         pterm.FgGreen,
+	"yellow":        pterm.FgYellow,
+	"blue":          pterm.FgBlue,
+	"magenta":       pterm.FgMagenta,
+	"cyan":          pterm.FgCyan,
+	"white":         pterm.FgWhite,
+	"gray":          pterm.FgGray,
+	"light-red":     pterm.FgLightRed,
+	"light-green":   pterm.FgLightGreen,
+	"light-yellow":  pterm.FgLightYellow,
+	"light-blue":    pterm.FgLightBlue,
+	"light-magenta": pterm.FgLightMagenta,
+	"light-cyan":    pterm.FgLightCyan,
+	"light-white":   pterm.FgLightWhite,
+}
+
+// RankColor maps a rank color name to a terminal color. Unknown names use
+// the terminal's default.
+func RankColor(name string) pterm.Color {
+	if color, ok := rankColors[strings.ToLower(name)]; ok {
+		return color
+	}
+	return pterm.FgDefault
+}
+
+// ValidRankColor reports whether name is one of the rank color names.
+func ValidRankColor(name string) bool {
+	_, ok := rankColors[strings.ToLower(name)]
+	return ok
+}
+
 type Console struct {
 	Silent bool
 }
@@ -26,7 +63,7 @@ func (c *Console) RenderLogo() {
 		pterm.NewLettersFromStringWithStyle("GO", pterm.NewStyle(pterm.FgCyan)),
 		pterm.NewLettersFromStringWithStyle("HL", pterm.NewStyle(pterm.FgLightMagenta)),
 	).Render()
-	pterm.Println(pterm.Cyan("Game of Homelab") + " - " + pterm.LightMagenta("v0.1.0"))
+	pterm.Println(pterm.Cyan("Game of Homelab") + " - " + pterm.LightMagenta("v"+version.Agent))
 	fmt.Println()
 }
 
@@ -97,7 +134,14 @@ func (c *Console) RenderReport(report *api.ScanReport) {
 	}
 }
 
-func (c *Console) RenderGrandTotal(score, maxScore int, rankName string, rankColor pterm.Color, previousScore int) {
+func (c *Console) RenderTable(rows [][]string) {
+	if c.Silent {
+		return
+	}
+	pterm.DefaultTable.WithHasHeader().WithBoxed().WithData(pterm.TableData(rows)).Render()
+}
+
+func (c *Console) RenderGrandTotal(score, maxScore int, rankName string, rankColor pterm.Color, previousScore int, xp *game.Progress) {
 	if c.Silent {
 		return
 	}
@@ -108,16 +152,28 @@ func (c *Console) RenderGrandTotal(score, maxScore int, rankName string, rankCol
 	if previousScore != -1 {
 		diff := score - previousScore
 		if diff > 0 {
-			text += pterm.Green(fmt.Sprintf(" (+%d XP 📈)", diff))
+			text += pterm.Green(fmt.Sprintf(" (+%d 📈)", diff))
 		} else if diff < 0 {
-			text += pterm.Red(fmt.Sprintf(" (%d XP 📉)", diff))
+			text += pterm.Red(fmt.Sprintf(" (%d 📉)", diff))
 		} else {
-			text += pterm.Gray(" (+0 XP)")
+			text += pterm.Gray(" (±0)")
 		}
 	}
 
 	text += fmt.Sprintf("\n\nRANK: %s", rankName)
 
+	if xp != nil {
+		text += fmt.Sprintf("\nLEVEL %d: %d / %d XP", xp.Level, xp.LevelXP, xp.NextLevelXP)
+		if xp.Gained > 0 {
+			text += pterm.Green(fmt.Sprintf(" (+%d XP)", xp.Gained))
+		} else if xp.Gained < 0 {
+			text += pterm.Red(fmt.Sprintf(" (%d XP)", xp.Gained))
+		}
+		if xp.LeveledUp {
+			text += pterm.LightMagenta(" LEVEL UP! ⬆️")
+		}
+	}
+
 	panel := pterm.DefaultBox.
 		WithTitle("🏆 GAME OVER SUMMARY").
 		WithTitleBottomCenter().
@@ -133,7 +189,121 @@ func (c *Console) Spacer() {
 	}
 }
 
-func (c *Console) PrintFinalResults(report api.GrandReport, asJson bool, previousScore int) {
+func (c *Console) RenderProviders(runs []game.ProviderRun) {
+	if c.Silent || len(runs) == 0 {
+		return
+	}
+
+	pterm.DefaultSection.Println("Providers")
+
+	rows := [][]string{{"NAME", "PLUGIN", "VERSION", "DURATION", "STATUS"}}
+	for _, run := range runs {
+		status := pterm.FgGreen.Sprint("OK")
+		if run.Partial {
+			status = pterm.FgYellow.Sprint("PARTIAL")
+		} else if run.Error != "" {
+			status = pterm.FgRed.Sprint("FAILED")
+		}
+
+		version := run.Plugin.Version
+		if version == "" {
+			version = "unknown"
+		}
+
+		rows = append(rows, []string{run.Name, run.Plugin.Name, version, run.Duration, status})
+	}
+
+	c.RenderTable(rows)
+}
+
+func (c *Console) RenderWarnings(warnings []game.Warning) {
+	if c.Silent || len(warnings) == 0 {
+		return
+	}
+
+	pterm.DefaultSection.Println("Validation Warnings")
+	for _, warning := range warnings {
+		pterm.Warning.Println(warning.String())
+	}
+	fmt.Println()
+}
+
+func (c *Console) RenderCategories(categories []game.CategoryScore) {
+	if c.Silent || len(categories) == 0 {
+		return
+	}
+
+	pterm.DefaultSection.Println("Categories")
+
+	rows := [][]string{{"CATEGORY", "WEIGHT", "CHECKS", "FAILED", "SCORE"}}
+	for _, category := range categories {
+		failed := fmt.Sprint(category.Failed)
+		if category.Critical > 0 {
+			failed = pterm.FgRed.Sprintf("%d (%d critical)", category.Failed, category.Critical)
+		}
+		rows = append(rows, []string{
+			category.Name,
+			fmt.Sprintf("%g", category.Weight),
+			fmt.Sprint(category.Checks),
+			failed,
+			fmt.Sprintf("%d/100", category.Score),
+		})
+	}
+
+	c.RenderTable(rows)
+	fmt.Println()
+}
+
+// RenderQuestUpdate summarizes which quests this scan completed, reopened,
+// started or retired.
+func (c *Console) RenderQuestUpdate(update *game.QuestUpdate) {
+	if c.Silent || update == nil {
+		return
+	}
+	if len(update.Completed)+len(update.Regressed)+len(update.Opened)+len(update.Retired) == 0 {
+		return
+	}
+
+	pterm.DefaultSection.Println("Quest Log")
+	for _, quest := range update.Completed {
+		pterm.Println(pterm.Green("✔ Completed: ") + quest.Name)
+	}
+	for _, quest := range update.Regressed {
+		pterm.Println(pterm.Red("↺ Regressed: ") + quest.Name)
+	}
+	for _, quest := range update.Opened {
+		pterm.Println(pterm.Yellow("+ New quest: ") + quest.Name)
+	}
+	for _, quest := range update.Retired {
+		pterm.Println(pterm.Gray("- Retired: ") + quest.Name)
+	}
+	fmt.Println()
+}
+
+// RenderAchievements announces the badges unlocked by this scan.
+func (c *Console) RenderAchievements(achievements []game.Achievement) {
+	if c.Silent {
+		return
+	}
+
+	var unlocked []game.Achievement
+	for _, achievement := range achievements {
+		if achievement.New {
+			unlocked = append(unlocked, achievement)
+		}
+	}
+	if len(unlocked) == 0 {
+		return
+	}
+
+	pterm.DefaultSection.Println(fmt.Sprintf("Achievements Unlocked (%d/%d)", len(achievements), len(game.Badges)))
+	for _, achievement := range unlocked {
+		pterm.Println(pterm.LightMagenta(fmt.Sprintf("%s %s", achievement.Icon, achievement.Name)) + " - " + achievement.Description)
+	}
+	fmt.Println()
+}
+
+func (c *Console) PrintFinalResults(report game.Report, asJson bool, previousScore int) {
 	if asJson {
 		jsonData, err := json.MarshalIndent(report, "", "  ")
 		if err != nil {
@@ -144,12 +314,22 @@ func (c *Console) PrintFinalResults(report api.GrandReport, asJson bool, previou
 	} else {
 		fmt.Println()
 
+		c.RenderProviders(report.Providers)
+		fmt.Println()
+
+		c.RenderWarnings(report.Warnings)
+
 		for _, pluginReport := range report.PluginReports {
 			c.RenderReport(pluginReport)
 			fmt.Println()
 		}
 
-		_, rankColor := game.GetRank(report.TotalScore, report.MaxScore)
-		c.RenderGrandTotal(report.TotalScore, report.MaxScore, report.Rank, rankColor, previousScore)
+		c.RenderCategories(report.Categories)
+
+		c.RenderQuestUpdate(report.Quests)
+
+		c.RenderAchievements(report.Achievements)
+
+		c.RenderGrandTotal(report.Score, 100, report.Rank, RankColor(report.RankColor), previousScore, report.XP)
 	}
 }
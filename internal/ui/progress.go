@@ -0,0 +1,105 @@
+package ui
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/pterm/pterm"
+
+	"github.com/danielvollbro/gohl/pkg/plugin"
+)
+
+// Progress renders one live line per provider while providers run in
+// parallel. A nil *Progress is valid and prints nothing.
+type Progress struct {
+	mu       sync.Mutex
+	multi    *pterm.MultiPrinter
+	spinners map[string]*pterm.SpinnerPrinter
+	checks   map[string]int
+}
+
+func (c *Console) StartProgress(names []string) *Progress {
+	if c.Silent {
+		return nil
+	}
+
+	multi := pterm.DefaultMultiPrinter
+	p := &Progress{
+		multi:    &multi,
+		spinners: make(map[string]*pterm.SpinnerPrinter),
+		checks:   make(map[string]int),
+	}
+
+	for _, name := range names {
+		spinner, err := pterm.DefaultSpinner.WithWriter(p.multi.NewWriter()).Start(fmt.Sprintf("%s: waiting", name))
+		if err == nil {
+			p.spinners[name] = spinner
+		}
+	}
+
+	p.multi.Start()
+	return p
+}
+
+func (p *Progress) Running(name string) {
+	p.update(name, func(s *pterm.SpinnerPrinter) { s.UpdateText(fmt.Sprintf("%s: running...", name)) })
+}
+
+// Update shows what a streaming provider is doing: the last finished check,
+// or its own progress message, with a counter when the total is known.
+func (p *Progress) Update(name string, progress plugin.Progress) {
+	p.update(name, func(s *pterm.SpinnerPrinter) {
+		text := progress.Message
+		if progress.Check != nil {
+			p.checks[name]++
+			mark := pterm.Green("✓")
+			if !progress.Check.Passed {
+				mark = pterm.Red("✗")
+			}
+			text = fmt.Sprintf("%s %s", mark, progress.Check.Name)
+		}
+
+		counter := ""
+		switch {
+		case progress.Total > 0:
+			counter = fmt.Sprintf(" [%d/%d]", progress.Current, progress.Total)
+		case progress.Check != nil:
+			counter = fmt.Sprintf(" [%d]", p.checks[name])
+		}
+
+		s.UpdateText(fmt.Sprintf("%s: running...%s %s", name, counter, text))
+	})
+}
+
+func (p *Progress) Done(name, message string) {
+	p.update(name, func(s *pterm.SpinnerPrinter) { s.Success(fmt.Sprintf("%s: %s", name, message)) })
+}
+
+func (p *Progress) Fail(name, message string) {
+	p.update(name, func(s *pterm.SpinnerPrinter) {
+		if kept := p.checks[name]; kept > 0 {
+			message = fmt.Sprintf("%s (kept %d streamed checks)", message, kept)
+		}
+		s.Fail(fmt.Sprintf("%s: %s", name, message))
+	})
+}
+
+func (p *Progress) Stop() {
+	if p == nil {
+		return
+	}
+	p.multi.Stop()
+}
+
+func (p *Progress) update(name string, fn func(*pterm.SpinnerPrinter)) {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if spinner, ok := p.spinners[name]; ok {
+		fn(spinner)
+	}
+}
@@ -0,0 +1,11 @@
+package version
+
+// Agent is the gohl release, set at build time with
+// -ldflags "-X github.com/danielvollbro/gohl/internal/version.Agent=1.2.3";
+// goreleaser does this for releases. Other builds report "dev", which
+// version requirements of providers do not hold back.
+var Agent = "dev"
+
+// API is the gohl-api schema version the agent reads and writes. Keep it in
+// step with the gohl-api requirement in go.mod.
+const API = "0.4.0"
@@ -0,0 +1,31 @@
+package plugin
+
+import (
+	"context"
+
+	api "github.com/danielvollbro/gohl-api"
+)
+
+// Progress is reported by scanners while Analyze is running. Check is set
+// when a single check has finished; Message, Current and Total describe
+// where the scanner is in its run.
+type Progress struct {
+	Message string
+	Current int
+	Total   int
+	Check   *api.CheckResult
+}
+
+type progressKey struct{}
+
+// WithProgress returns a context whose scanners report progress to fn.
+func WithProgress(ctx context.Context, fn func(Progress)) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+// ReportProgress passes p to the callback installed with WithProgress, if any.
+func ReportProgress(ctx context.Context, p Progress) {
+	if fn, ok := ctx.Value(progressKey{}).(func(Progress)); ok {
+		fn(p)
+	}
+}
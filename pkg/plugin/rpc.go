@@ -0,0 +1,177 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"sync/atomic"
+	"time"
+
+	api "github.com/danielvollbro/gohl-api"
+)
+
+// RPCFlag starts a provider as a long-running plugin that serves the Scanner
+// interface as JSON-RPC over stdin/stdout.
+const RPCFlag = "--gohl-rpc"
+
+const (
+	// MaxResponseSize bounds what the agent reads from a plugin for a single
+	// call, so a misbehaving plugin cannot make it buffer unlimited output.
+	MaxResponseSize = 16 << 20
+
+	// InfoTimeout bounds Info, which has no context of its own.
+	InfoTimeout = 5 * time.Second
+)
+
+// ErrResponseTooLarge is returned by calls once a plugin sent more than
+// MaxResponseSize bytes for one response. The connection is unusable after.
+var ErrResponseTooLarge = errors.New("plugin response exceeds the size limit")
+
+type AnalyzeArgs struct {
+	Config map[string]string
+}
+
+type Empty struct{}
+
+type rpcServer struct {
+	scanner Scanner
+}
+
+func (s *rpcServer) Info(_ Empty, reply *api.PluginInfo) error {
+	*reply = s.scanner.Info()
+	return nil
+}
+
+func (s *rpcServer) Analyze(args AnalyzeArgs, reply *api.ScanReport) error {
+	report, err := s.scanner.Analyze(context.Background(), args.Config)
+	if err != nil {
+		return err
+	}
+	if report == nil {
+		return fmt.Errorf("scanner returned no report")
+	}
+	*reply = *report
+	return nil
+}
+
+func (s *rpcServer) Ping(_ Empty, reply *string) error {
+	*reply = "pong"
+	return nil
+}
+
+type stdio struct{}
+
+func (stdio) Read(p []byte) (int, error)  { return os.Stdin.Read(p) }
+func (stdio) Write(p []byte) (int, error) { return os.Stdout.Write(p) }
+func (stdio) Close() error                { return os.Stdin.Close() }
+
+// Serve runs s as an RPC plugin on stdin/stdout and returns once the agent
+// closes the connection. Providers call it when started with RPCFlag.
+func Serve(s Scanner) {
+	ServeConn(s, stdio{})
+}
+
+func ServeConn(s Scanner, conn io.ReadWriteCloser) {
+	server := rpc.NewServer()
+	if err := server.RegisterName("Scanner", &rpcServer{scanner: s}); err != nil {
+		panic(err)
+	}
+	server.ServeCodec(jsonrpc.NewServerCodec(conn))
+}
+
+// RPCClient is the agent side of a plugin served with Serve. It implements
+// Scanner, so remote providers can be used like compiled-in ones.
+type RPCClient struct {
+	client *rpc.Client
+	conn   *cappedConn
+}
+
+func NewRPCClient(conn io.ReadWriteCloser) *RPCClient {
+	return newRPCClient(conn, MaxResponseSize)
+}
+
+func newRPCClient(conn io.ReadWriteCloser, limit int64) *RPCClient {
+	capped := &cappedConn{ReadWriteCloser: conn, limit: limit}
+	return &RPCClient{client: jsonrpc.NewClient(capped), conn: capped}
+}
+
+// Info asks the plugin who it is, giving up after InfoTimeout.
+func (c *RPCClient) Info() api.PluginInfo {
+	ctx, cancel := context.WithTimeout(context.Background(), InfoTimeout)
+	defer cancel()
+
+	info, err := c.InfoContext(ctx)
+	if err != nil {
+		return api.PluginInfo{}
+	}
+	return info
+}
+
+func (c *RPCClient) InfoContext(ctx context.Context) (api.PluginInfo, error) {
+	var info api.PluginInfo
+	if err := c.call(ctx, "Scanner.Info", Empty{}, &info); err != nil {
+		return api.PluginInfo{}, err
+	}
+	return info, nil
+}
+
+func (c *RPCClient) Analyze(ctx context.Context, config map[string]string) (*api.ScanReport, error) {
+	var report api.ScanReport
+	if err := c.call(ctx, "Scanner.Analyze", AnalyzeArgs{Config: config}, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+func (c *RPCClient) Ping(ctx context.Context) error {
+	var reply string
+	return c.call(ctx, "Scanner.Ping", Empty{}, &reply)
+}
+
+func (c *RPCClient) Close() error {
+	return c.client.Close()
+}
+
+// call gives up waiting when ctx ends. net/rpc cannot cancel the remote side,
+// so callers are expected to restart the plugin after a timeout.
+func (c *RPCClient) call(ctx context.Context, method string, args, reply interface{}) error {
+	call := c.client.Go(method, args, reply, make(chan *rpc.Call, 1))
+	select {
+	case <-call.Done:
+		c.conn.reset()
+		return call.Error
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// cappedConn fails reads with ErrResponseTooLarge once more than limit bytes
+// arrived since the last completed call. Calls may overlap, e.g. a ping
+// during a scan, so the limit is per response only approximately.
+type cappedConn struct {
+	io.ReadWriteCloser
+	limit int64
+	read  atomic.Int64
+}
+
+func (c *cappedConn) Read(p []byte) (int, error) {
+	remaining := c.limit - c.read.Load()
+	if remaining <= 0 {
+		return 0, ErrResponseTooLarge
+	}
+	if int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+
+	n, err := c.ReadWriteCloser.Read(p)
+	c.read.Add(int64(n))
+	return n, err
+}
+
+func (c *cappedConn) reset() {
+	c.read.Store(0)
+}
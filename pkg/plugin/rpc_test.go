@@ -0,0 +1,108 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	api "github.com/danielvollbro/gohl-api"
+)
+
+type fakeScanner struct {
+	block chan struct{}
+	fail  bool
+	huge  bool
+}
+
+func (f *fakeScanner) Info() api.PluginInfo {
+	return api.PluginInfo{ID: "fake", Name: "Fake", Version: "1.0.0"}
+}
+
+func (f *fakeScanner) Analyze(ctx context.Context, config map[string]string) (*api.ScanReport, error) {
+	if f.block != nil {
+		<-f.block
+	}
+	if f.fail {
+		return nil, errors.New("boom")
+	}
+	if f.huge {
+		return &api.ScanReport{PluginID: strings.Repeat("x", 1<<20)}, nil
+	}
+	return &api.ScanReport{
+		PluginID: "fake",
+		Checks:   []api.CheckResult{{ID: "fake-" + config["name"], Passed: true, Score: 1, MaxScore: 1}},
+	}, nil
+}
+
+func startPipe(t *testing.T, s Scanner) *RPCClient {
+	server, conn := net.Pipe()
+	go ServeConn(s, server)
+
+	client := NewRPCClient(conn)
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestRPCRoundTrip(t *testing.T) {
+	client := startPipe(t, &fakeScanner{})
+
+	if info := client.Info(); info.ID != "fake" || info.Version != "1.0.0" {
+		t.Fatalf("unexpected info: %+v", info)
+	}
+
+	if err := client.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping failed: %v", err)
+	}
+
+	report, err := client.Analyze(context.Background(), map[string]string{"name": "check"})
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if len(report.Checks) != 1 || report.Checks[0].ID != "fake-check" {
+		t.Errorf("unexpected report: %+v", report)
+	}
+}
+
+func TestRPCAnalyzeError(t *testing.T) {
+	client := startPipe(t, &fakeScanner{fail: true})
+
+	_, err := client.Analyze(context.Background(), nil)
+	if err == nil || err.Error() != "boom" {
+		t.Errorf("expected remote error 'boom', got %v", err)
+	}
+}
+
+func TestRPCCallHonoursContext(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	client := startPipe(t, &fakeScanner{block: block})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := client.Analyze(ctx, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestRPCResponseLimit(t *testing.T) {
+	server, conn := net.Pipe()
+	go ServeConn(&fakeScanner{huge: true}, server)
+
+	client := newRPCClient(conn, 64<<10)
+	t.Cleanup(func() { client.Close() })
+
+	// Small responses reset the budget, so many of them fit.
+	for i := 0; i < 1000; i++ {
+		if _, err := client.InfoContext(context.Background()); err != nil {
+			t.Fatalf("Info call %d failed: %v", i, err)
+		}
+	}
+
+	if _, err := client.Analyze(context.Background(), nil); !errors.Is(err, ErrResponseTooLarge) {
+		t.Errorf("expected ErrResponseTooLarge, got %v", err)
+	}
+}